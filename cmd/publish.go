@@ -7,8 +7,12 @@ package cmd
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/getsentry/sentry-go"
 	"github.com/latency-lingo/cli/internal"
@@ -16,12 +20,21 @@ import (
 )
 
 var (
-	dataFile    string
-	reportLabel string
-	environment string
-	apiKey      string
-	rawSamples  bool
-	format      string
+	dataFiles     []string
+	reportLabel   string
+	environment   string
+	apiKey        string
+	rawSamples    bool
+	format        string
+	configFile    string
+	config        *internal.Config
+	filters       internal.FilterConfig
+	steadyState   string
+	generatorFrom string
+	perGenerator  bool
+	labelBy       string
+	startedAt     string
+	parseOptions  internal.ParseOptions
 )
 
 // PublishCmd represents the publish command
@@ -38,13 +51,37 @@ var PublishCmd = &cobra.Command{
 			log.Fatalln("Received unknown environment", environment)
 		}
 
-		InfoLog.Println("Parsing provided file", dataFile)
+		if steadyState != "" && steadyState != "auto" {
+			log.Fatalln("Received unknown steady state mode", steadyState)
+		}
+
+		if generatorFrom != internal.GeneratorFromFile && generatorFrom != internal.GeneratorFromThread {
+			log.Fatalln("Received unknown generator source", generatorFrom)
+		}
+
 		var (
-			reportPath string
-			runId      string
-			err        error
+			runId string
+			err   error
 		)
 
+		dataFiles, err = expandDataFiles(dataFiles)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		InfoLog.Println("Parsing provided file(s)", strings.Join(dataFiles, ", "))
+
+		config, err = internal.LoadConfig(configFile)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		mergeFilterFlags(&config.Filters)
+
+		parseOptions = internal.ParseOptions{LabelBy: labelBy, DefaultLabel: reportLabel}
+		parseOptions.StartedAt, err = internal.ParseStartedAt(startedAt)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
 		if rawSamples {
 			runId, err = publishRawSamples()
 		} else {
@@ -57,44 +94,89 @@ var PublishCmd = &cobra.Command{
 			os.Exit(1)
 			return
 		}
-		reportPath = "test-runs/" + runId
-
-		switch environment {
-		case "production":
-			InfoLog.Printf("Report can be found at https://latencylingo.com/%s", reportPath)
-		case "development":
-			InfoLog.Printf("Report can be found at http://localhost:3000/%s", reportPath)
-		}
+		InfoLog.Printf("Report can be found at %s", internal.ReportURL(environment, runId))
 	},
 }
 
 func init() {
-	PublishCmd.Flags().StringVar(&dataFile, "file", "", "Test results file to parse and publish.")
+	PublishCmd.Flags().StringArrayVar(&dataFiles, "file", nil, "Test results file to parse and publish. Can be repeated or a glob to merge results from multiple load generators.")
 	PublishCmd.Flags().StringVar(&reportLabel, "label", "", "Test scenario name for this run.")
 	PublishCmd.Flags().StringVar(&environment, "env", "production", "Environment for API communication. Supported values: development, production.")
 	PublishCmd.Flags().StringVar(&apiKey, "api-key", "", "API key to associate test runs with a user. Sign up to get one at https://latencylingo.com/account/api-access")
 	PublishCmd.Flags().BoolVar(&rawSamples, "all-samples", false, "Publish all samples instead of pre-aggregated metrics.")
-	PublishCmd.Flags().StringVar(&format, "format", "jmeter", "Format of the provided file. Supported values: jmeter, k6, locust, gatling.")
+	PublishCmd.Flags().StringVar(&format, "format", "jmeter", formatUsage())
+	PublishCmd.RegisterFlagCompletionFunc("format", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return append(internal.ParserNames(), internal.FormatAuto), cobra.ShellCompDirectiveNoFileComp
+	})
+	PublishCmd.Flags().StringVar(&configFile, "config", "", "JSON file with label normalization rules and filters.")
+	PublishCmd.Flags().StringArrayVar(&filters.IncludeLabels, "include-label", nil, "Only publish labels matching this regex. Can be repeated.")
+	PublishCmd.Flags().StringArrayVar(&filters.ExcludeLabels, "exclude-label", nil, "Drop labels matching this regex. Can be repeated.")
+	PublishCmd.Flags().StringVar(&filters.From, "from", "", "Drop data before this timestamp or offset from the start of the run, eg. 2m.")
+	PublishCmd.Flags().StringVar(&filters.To, "to", "", "Drop data after this timestamp or offset from the start of the run, eg. 30m.")
+	PublishCmd.Flags().StringVar(&filters.SkipFirst, "skip-first", "", "Warm-up period to drop from the start of the run, eg. 2m.")
+	PublishCmd.Flags().StringArrayVar(&filters.ExcludeDataTypes, "exclude-data-type", nil, "Drop JMeter samples with this dataType. Can be repeated.")
+	PublishCmd.Flags().StringArrayVar(&filters.ExcludeResponseCodes, "exclude-response-code", nil, "Drop samples with this response code. Can be repeated.")
+	PublishCmd.Flags().StringArrayVar(&filters.ExcludeThreadNames, "exclude-thread-name", nil, "Drop JMeter samples with a threadName matching this regex. Can be repeated.")
+	PublishCmd.Flags().StringVar(&steadyState, "steady-state", "", "Detect the steady state window and summarize it separately. Supported values: auto.")
+	PublishCmd.Flags().StringVar(&generatorFrom, "generator-from", internal.GeneratorFromFile, "How to identify load generators. Supported values: file, thread (JMeter threadName host prefix).")
+	PublishCmd.Flags().BoolVar(&perGenerator, "per-generator", false, "Publish a chart metric breakdown per load generator.")
+	PublishCmd.Flags().StringVar(&labelBy, "label-by", "", "How to label k6 and vegeta requests. Supported values for k6: name, group, scenario. For vegeta: method-url, url, attack.")
+	PublishCmd.Flags().StringVar(&startedAt, "started-at", "", "Start of the run for formats without time stamps, eg. 2022-03-17T10:00:12Z. Required for k6-summary, used instead of the file modification time for wrk and hey.")
 	PublishCmd.MarkFlagRequired("file")
 	PublishCmd.MarkFlagRequired("api-key")
 	PublishCmd.MarkFlagRequired("label")
 }
 
+// formatUsage lists the registered formats, including formats registered by
+// programs embedding the CLI after this package is initialized.
+func formatUsage() string {
+	var formats []string
+	for _, parser := range internal.Parsers() {
+		formats = append(formats, fmt.Sprintf("%s (%s)", parser.Name(), parser.Description()))
+	}
+	return fmt.Sprintf("Format of the provided file. Supported values: %s, or %s to detect it.", strings.Join(formats, ", "), internal.FormatAuto)
+}
+
 func publishRawSamples() (string, error) {
-	samples, err := internal.ParseDataFileSamples(dataFile)
+	var streams [][]internal.LingoSample
+	for _, file := range dataFiles {
+		options := parseOptions
+		options.Samples = true
+		parsed, err := internal.ParseDataFile(file, format, options)
+		if err != nil {
+			return "", err
+		}
+		samples := parsed.Samples
+
+		for i := range samples {
+			samples[i].Generator = internal.InferGenerator(file, samples[i].ThreadName, generatorFrom)
+		}
+		streams = append(streams, samples)
+	}
+	samples := internal.MergeSamples(streams)
+
+	filter, err := internal.NewFilter(config.Filters)
 	if err != nil {
 		return "", err
 	}
+	samples = internal.FilterSamples(samples, filter)
+	if len(samples) == 0 {
+		return "", errors.New("no samples left after applying filters")
+	}
 
-	testRun, err := internal.CreateTestRun(
-		hostName(environment),
-		apiKey,
-		reportLabel,
-		samples[0].TimeStamp/1000,
-		0,
+	normalizer, err := internal.NewLabelNormalizer(config.Labels)
+	if err != nil {
+		return "", err
+	}
+	internal.NormalizeSampleLabels(samples, normalizer)
+
+	testRun, err := internal.CreateTestRun(hostName(environment), internal.CreateTestRunRequestData{
+		ApiKey:       apiKey,
+		ScenarioName: reportLabel,
+		StartedAt:    samples[0].TimeStamp / 1000,
 		// TODO(bobsin): make this more accurate.
-		"listener",
-	)
+		PublishStrategy: "listener",
+	})
 	if err != nil {
 		return "", err
 	}
@@ -125,13 +207,104 @@ func publishRawSamples() (string, error) {
 }
 
 func publishV2() (string, error) {
-	rows, err := internal.ParseDataFile(dataFile, format)
+	var (
+		streams       [][]internal.UngroupedMetricDataPoint
+		customMetrics []internal.CustomMetricPoint
+		errorEvents   []internal.ErrorEvent
+		metadata      *internal.RunMetadata
+		aggregated    []*internal.ParsedData
+	)
+	for _, file := range dataFiles {
+		parsed, err := internal.ParseDataFile(file, format, parseOptions)
+		if err != nil {
+			return "", err
+		}
+
+		if parsed.Summary != nil || len(parsed.Buckets) > 0 {
+			for i := range parsed.Buckets {
+				parsed.Buckets[i].Generator = internal.InferGenerator(file, "", generatorFrom)
+			}
+			aggregated = append(aggregated, parsed)
+			continue
+		}
+
+		rows := parsed.Rows
+		for i := range rows {
+			rows[i].Generator = internal.InferGenerator(file, rows[i].ThreadName, generatorFrom)
+		}
+		streams = append(streams, rows)
+		customMetrics = append(customMetrics, parsed.CustomMetrics...)
+		errorEvents = append(errorEvents, parsed.Errors...)
+		if metadata == nil {
+			metadata = parsed.Metadata
+		}
+	}
+	if len(aggregated) > 0 {
+		if len(aggregated) < len(dataFiles) {
+			return "", errors.New("pre-aggregated results can't be merged with results that have requests")
+		}
+		return publishSummary(aggregated)
+	}
+	rows := internal.MergeDataPoints(streams)
+
+	filter, err := internal.NewFilter(config.Filters)
+	if err != nil {
+		return "", err
+	}
+	rows = internal.FilterDataPoints(rows, filter)
+	if len(rows) == 0 {
+		return "", errors.New("no rows left after applying filters")
+	}
+
+	normalizer, err := internal.NewLabelNormalizer(config.Labels)
 	if err != nil {
 		return "", err
 	}
+	keptLabels := internal.NormalizeDataPointLabels(rows, normalizer)
+	internal.ApplyApdexThresholds(rows, config.Apdex)
 	groupedResult := internal.GroupAllDataPoints(rows)
 
-	testRun, err := internal.CreateTestRun(hostName(environment), apiKey, reportLabel, rows[0].TimeStamp, rows[len(rows)-1].TimeStamp, "file")
+	customMetrics = internal.TrimCustomMetrics(customMetrics, rows[0].TimeStamp, rows[len(rows)-1].TimeStamp)
+	customMetrics = internal.FilterCustomMetrics(customMetrics, filter)
+	internal.NormalizeCustomMetricLabels(customMetrics, normalizer, keptLabels)
+
+	errorEvents = internal.TrimErrorEvents(errorEvents, rows[0].TimeStamp, rows[len(rows)-1].TimeStamp)
+	errorEvents = internal.FilterErrorEvents(errorEvents, filter)
+	internal.NormalizeErrorEventLabels(errorEvents, normalizer, keptLabels)
+	errorEvents = append(errorEvents, internal.ErrorEventsFromRows(rows)...)
+
+	// rows are stamped in milliseconds, runs in seconds
+	runData := internal.CreateTestRunRequestData{
+		ApiKey:          apiKey,
+		ScenarioName:    reportLabel,
+		StartedAt:       rows[0].TimeStamp / 1000,
+		StoppedAt:       rows[len(rows)-1].TimeStamp / 1000,
+		PublishStrategy: "file",
+	}
+
+	if metadata != nil {
+		runData.RunName = metadata.Name
+		// the tool's start time includes the time before the first request, unless it was filtered out
+		trimmed := config.Filters.From != "" || config.Filters.SkipFirst != ""
+		if !trimmed && metadata.StartedAt > 0 && metadata.StartedAt < rows[0].TimeStamp {
+			runData.StartedAt = metadata.StartedAt / 1000
+		}
+	}
+
+	var steadyStateWindow *internal.SteadyStateWindow
+	if steadyState == "auto" {
+		window, ok := internal.DetectSteadyState(groupedResult.DataPoints)
+		if ok {
+			steadyStateWindow = window
+			runData.SteadyStateStartedAt = window.StartedAt / 1000
+			runData.SteadyStateStoppedAt = window.StoppedAt / 1000
+			InfoLog.Println("Detected steady state from", formatTimeStamp(window.StartedAt), "to", formatTimeStamp(window.StoppedAt))
+		} else {
+			InfoLog.Println("Unable to detect a steady state, only the full run will be summarized")
+		}
+	}
+
+	testRun, err := internal.CreateTestRun(hostName(environment), runData)
 	if err != nil {
 		return "", err
 	}
@@ -140,10 +313,17 @@ func publishV2() (string, error) {
 
 	InfoLog.Println("Created a new test run with ID", runId, "under scenario", testRun.ScenarioId)
 
+	dataPoints := groupedResult.DataPoints
+	if perGenerator {
+		for _, generatorDataPoints := range groupedResult.DataPointsByGenerator {
+			dataPoints = append(dataPoints, generatorDataPoints...)
+		}
+	}
+
 	if _, err := internal.CreateTestChartMetrics(
 		hostName(environment),
 		runToken,
-		groupedResult.DataPoints,
+		dataPoints,
 		groupedResult.DataPointsByLabel,
 	); err != nil {
 		return "", err
@@ -153,7 +333,26 @@ func publishV2() (string, error) {
 	for _, dp := range groupedResult.DataPointsByLabel {
 		labeledDpCount += len(dp)
 	}
-	InfoLog.Println("Published", len(groupedResult.DataPoints)+labeledDpCount, "chart metric rows")
+	InfoLog.Println("Published", len(dataPoints)+labeledDpCount, "chart metric rows")
+
+	if len(customMetrics) > 0 {
+		customDataPoints := internal.GroupCustomMetrics(customMetrics)
+		if _, err := internal.CreateTestCustomMetrics(hostName(environment), runToken, customDataPoints); err != nil {
+			return "", err
+		}
+
+		InfoLog.Println("Published", len(customDataPoints), "custom metric rows")
+	}
+
+	var errorBreakdown internal.ErrorBreakdown
+	if len(errorEvents) > 0 {
+		errorBreakdown = internal.CalculateErrorBreakdown(rows, errorEvents)
+		if _, err := internal.CreateTestErrorMetrics(hostName(environment), runToken, errorBreakdown); err != nil {
+			return "", err
+		}
+
+		InfoLog.Println("Published", len(errorBreakdown.Summaries)+len(errorBreakdown.DataPoints), "error metric rows")
+	}
 
 	metricSummary := internal.CalculateMetricSummaryOverall()
 	metricSummaryByLabel := internal.CalculateMetricSummaryByLabel()
@@ -168,6 +367,23 @@ func publishV2() (string, error) {
 	}
 
 	InfoLog.Println("Published", len(metricSummaryByLabel)+1, "summary metric rows")
+	printMetricSummary("Full run", metricSummary)
+	printErrorSummary(errorBreakdown)
+
+	if steadyStateWindow != nil {
+		steadySummary, steadySummaryByLabel := internal.CalculateMetricSummaryForWindow(rows, steadyStateWindow)
+		if _, err := internal.CreateTestSummaryMetrics(
+			hostName(environment),
+			runToken,
+			steadySummary,
+			steadySummaryByLabel,
+		); err != nil {
+			return "", err
+		}
+
+		InfoLog.Println("Published", len(steadySummaryByLabel)+1, "steady state summary metric rows")
+		printMetricSummary("Steady state", steadySummary)
+	}
 
 	result, err := internal.GetTestRunResults(hostName(environment), runToken)
 	if err != nil {
@@ -183,16 +399,174 @@ func publishV2() (string, error) {
 	return runId, nil
 }
 
+// publishSummary publishes a run for formats that report totals instead of
+// requests, with chart metrics when the format reports totals per period.
+// Periods from several files are merged, summaries can't be.
+func publishSummary(parsedFiles []*internal.ParsedData) (string, error) {
+	if err := checkPreAggregatedOptions(); err != nil {
+		return "", err
+	}
+
+	normalizer, err := internal.NewLabelNormalizer(config.Labels)
+	if err != nil {
+		return "", err
+	}
+
+	var buckets []internal.AggregatedBucket
+	for _, parsed := range parsedFiles {
+		buckets = append(buckets, parsed.Buckets...)
+	}
+	filtered := config.Filters.HasLabelFilters() || config.Filters.HasTimeBounds()
+
+	var summaryData *internal.SummaryData
+	if len(buckets) == 0 {
+		if len(parsedFiles) > 1 {
+			return "", errors.New("formats that only report a summary support a single file")
+		}
+		if filtered {
+			return "", errors.New("label and time filters aren't supported for formats that only report a summary")
+		}
+
+		summaryData = parsedFiles[0].Summary
+		summaryData.SummaryByLabel = internal.NormalizeSummaryLabels(summaryData.SummaryByLabel, normalizer)
+	} else {
+		filter, err := internal.NewFilter(config.Filters)
+		if err != nil {
+			return "", err
+		}
+		buckets = internal.FilterBuckets(buckets, filter)
+		if len(buckets) == 0 {
+			return "", errors.New("no periods left after applying filters")
+		}
+		internal.NormalizeBucketLabels(buckets, normalizer)
+
+		summary, summaryByLabel := internal.SummarizeAggregatedBuckets(buckets)
+		startedAt, stoppedAt := internal.AggregatedBucketsRange(buckets)
+		summaryData = &internal.SummaryData{
+			Summary:        summary,
+			SummaryByLabel: summaryByLabel,
+			StartedAt:      startedAt,
+			StoppedAt:      stoppedAt,
+		}
+
+		// the tool's own summary is exact, but only covers a whole single file
+		if tool := parsedFiles[0].Summary; tool != nil && len(parsedFiles) == 1 && !filtered {
+			summaryData = tool
+			summaryData.SummaryByLabel = internal.NormalizeSummaryLabels(tool.SummaryByLabel, normalizer)
+		}
+	}
+	summaryByLabel := summaryData.SummaryByLabel
+
+	testRun, err := internal.CreateTestRun(hostName(environment), internal.CreateTestRunRequestData{
+		ApiKey:          apiKey,
+		ScenarioName:    reportLabel,
+		StartedAt:       summaryData.StartedAt / 1000,
+		StoppedAt:       summaryData.StoppedAt / 1000,
+		PublishStrategy: "summary",
+	})
+	if err != nil {
+		return "", err
+	}
+	runId := testRun.ID
+	runToken := testRun.WriteToken
+
+	InfoLog.Println("Created a new test run with ID", runId, "under scenario", testRun.ScenarioId)
+
+	if len(buckets) > 0 {
+		groupedResult := internal.GroupAggregatedBuckets(buckets)
+		if _, err := internal.CreateTestChartMetrics(
+			hostName(environment),
+			runToken,
+			groupedResult.DataPoints,
+			groupedResult.DataPointsByLabel,
+		); err != nil {
+			return "", err
+		}
+
+		labeledDpCount := 0
+		for _, dp := range groupedResult.DataPointsByLabel {
+			labeledDpCount += len(dp)
+		}
+		InfoLog.Println("Published", len(groupedResult.DataPoints)+labeledDpCount, "chart metric rows")
+	}
+
+	if _, err := internal.CreateTestSummaryMetrics(
+		hostName(environment),
+		runToken,
+		summaryData.Summary,
+		summaryByLabel,
+	); err != nil {
+		return "", err
+	}
+
+	InfoLog.Println("Published", len(summaryByLabel)+1, "summary metric rows")
+	printMetricSummary("Full run", summaryData.Summary)
+
+	return runId, nil
+}
+
+// checkPreAggregatedOptions rejects options that need individual requests,
+// rather than ignoring them for formats that only report totals.
+func checkPreAggregatedOptions() error {
+	switch {
+	case steadyState != "":
+		return errors.New("--steady-state isn't supported for pre-aggregated formats")
+	case perGenerator:
+		return errors.New("--per-generator isn't supported for pre-aggregated formats")
+	case config.Apdex.Enabled():
+		return errors.New("apdex.thresholdMs and apdex.labels aren't supported for pre-aggregated formats")
+	case config.Filters.HasSampleFilters():
+		return errors.New("data type, response code and thread name filters aren't supported for pre-aggregated formats")
+	}
+	return nil
+}
+
+// mergeFilterFlags combines filters from the config file with command line
+// flags. Flags take precedence for time bounds.
+func mergeFilterFlags(config *internal.FilterConfig) {
+	config.IncludeLabels = append(config.IncludeLabels, filters.IncludeLabels...)
+	config.ExcludeLabels = append(config.ExcludeLabels, filters.ExcludeLabels...)
+	config.ExcludeDataTypes = append(config.ExcludeDataTypes, filters.ExcludeDataTypes...)
+	config.ExcludeResponseCodes = append(config.ExcludeResponseCodes, filters.ExcludeResponseCodes...)
+	config.ExcludeThreadNames = append(config.ExcludeThreadNames, filters.ExcludeThreadNames...)
+
+	if filters.From != "" {
+		config.From = filters.From
+	}
+	if filters.To != "" {
+		config.To = filters.To
+	}
+	if filters.SkipFirst != "" {
+		config.SkipFirst = filters.SkipFirst
+	}
+}
+
+// expandDataFiles resolves globs so results from multiple load generators can
+// be passed as eg. --file "results/*.jtl".
+func expandDataFiles(patterns []string) ([]string, error) {
+	var files []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(matches) == 0 {
+			// keep the original path so parsing reports a missing file
+			matches = []string{pattern}
+		}
+		files = append(files, matches...)
+	}
+
+	return files, nil
+}
+
 func hostName(env string) string {
-	switch env {
-	case "production":
-		return "https://latency-lingo.web.app"
-	case "development":
-		return "http://localhost:5000"
-	default:
+	host, err := internal.APIHost(env)
+	if err != nil {
 		log.Fatalln("User specified unknown environment", env)
-		return ""
 	}
+	return host
 }
 
 func initSentryScope() {
@@ -212,7 +586,7 @@ func initSentryScope() {
 	scope.SetContext("Flags", map[string]string{
 		"environment": environment,
 		"user":        userRef,
-		"dataFile":    dataFile,
+		"dataFile":    strings.Join(dataFiles, ","),
 		"reportLabel": reportLabel,
 		"version":     "2.0.0",
 	})
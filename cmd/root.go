@@ -47,6 +47,8 @@ func Execute() {
 		}
 	}()
 
+	PublishCmd.Flags().Lookup("format").Usage = formatUsage()
+
 	err := RootCmd.Execute()
 	if err != nil {
 		os.Exit(1)
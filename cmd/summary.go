@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/latency-lingo/cli/internal"
+)
+
+func printMetricSummary(title string, summary internal.MetricSummary) {
+	InfoLog.Printf(
+		"%s: %d requests, %d failures, %d max virtual users, latency avg %.2fms p50 %s p90 %s p95 %s p99 %s",
+		title,
+		summary.TotalRequests,
+		summary.TotalFailures,
+		summary.MaxVirtualUsers,
+		summary.Latencies.AvgMs,
+		formatPercentile(summary.Latencies, internal.Percentile50, summary.Latencies.P50Ms),
+		formatPercentile(summary.Latencies, internal.Percentile90, summary.Latencies.P90Ms),
+		formatPercentile(summary.Latencies, internal.Percentile95, summary.Latencies.P95Ms),
+		formatPercentile(summary.Latencies, internal.Percentile99, summary.Latencies.P99Ms),
+	)
+
+	if summary.Throughput != nil {
+		InfoLog.Printf(
+			"%s: throughput mean %.2f req/s peak %.2f req/s, received mean %.2f B/s peak %.2f B/s, sent mean %.2f B/s peak %.2f B/s, avg response size %.2f B",
+			title,
+			summary.Throughput.MeanRequestsPerSecond,
+			summary.Throughput.PeakRequestsPerSecond,
+			summary.Throughput.MeanBytesReceivedPerSecond,
+			summary.Throughput.PeakBytesReceivedPerSecond,
+			summary.Throughput.MeanBytesSentPerSecond,
+			summary.Throughput.PeakBytesSentPerSecond,
+			summary.Throughput.AvgResponseBytes,
+		)
+	}
+
+	if summary.Apdex != nil {
+		InfoLog.Printf(
+			"%s: apdex %.2f, %d satisfied, %d tolerating, %d frustrated",
+			title,
+			summary.Apdex.Score,
+			summary.Apdex.Satisfied,
+			summary.Apdex.Tolerating,
+			summary.Apdex.Frustrated,
+		)
+	}
+
+	if summary.LatencyBreakdown != nil {
+		InfoLog.Printf(
+			"%s: connect avg %.2fms p95 %.2fms, server avg %.2fms p95 %.2fms, idle avg %.2fms",
+			title,
+			summary.LatencyBreakdown.Connect.AvgMs,
+			summary.LatencyBreakdown.Connect.P95Ms,
+			summary.LatencyBreakdown.Server.AvgMs,
+			summary.LatencyBreakdown.Server.P95Ms,
+			summary.LatencyBreakdown.Idle.AvgMs,
+		)
+	}
+}
+
+// maxPrintedErrors limits the errors logged per category.
+const maxPrintedErrors = 5
+
+func printErrorSummary(breakdown internal.ErrorBreakdown) {
+	printed := make(map[string]int)
+	for _, summary := range breakdown.Summaries {
+		// summaries are sorted by count, only print the most frequent overall errors
+		if summary.Label != "" || printed[summary.Category] >= maxPrintedErrors {
+			continue
+		}
+
+		InfoLog.Printf("Errors by %s: %d x %s", summary.Category, summary.Count, summary.Value)
+		printed[summary.Category]++
+	}
+}
+
+func formatTimeStamp(millis uint64) string {
+	return time.UnixMilli(int64(millis)).Format(time.RFC3339)
+}
+
+// formatPercentile prints n/a for percentiles the results don't report.
+func formatPercentile(latencies *internal.Latencies, percentile internal.Percentiles, value float64) string {
+	if latencies.Unreported&percentile != 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.2fms", value)
+}
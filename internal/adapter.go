@@ -8,12 +8,43 @@ import (
 	"github.com/getsentry/sentry-go"
 )
 
+// SteadyStateScope marks summaries calculated over the detected steady state
+// instead of the full run.
+const SteadyStateScope = "steady-state"
+
 type MetricSummary struct {
-	Label           string     `json:"label"`
-	Latencies       *Latencies `json:"latencies"`
-	TotalRequests   uint64     `json:"totalRequests"`
-	TotalFailures   uint64     `json:"totalFailures"`
-	MaxVirtualUsers uint64     `json:"maxVirtualUsers"`
+	Label           string             `json:"label"`
+	Scope           string             `json:"scope,omitempty"`
+	Latencies       *Latencies         `json:"latencies"`
+	TotalRequests   uint64             `json:"totalRequests"`
+	TotalFailures   uint64             `json:"totalFailures"`
+	MaxVirtualUsers uint64             `json:"maxVirtualUsers"`
+	Throughput      *ThroughputSummary `json:"throughput"`
+	// LatencyBreakdown is nil when the format doesn't report request timings.
+	LatencyBreakdown *LatencyBreakdown `json:"latencyBreakdown"`
+	// Apdex is nil unless an Apdex threshold is configured.
+	Apdex *Apdex `json:"apdex"`
+	// Transaction marks labels that group other requests.
+	Transaction bool `json:"transaction"`
+}
+
+type ThroughputSummary struct {
+	MeanRequestsPerSecond      float64 `json:"meanRequestsPerSecond"`
+	PeakRequestsPerSecond      float64 `json:"peakRequestsPerSecond"`
+	MeanBytesReceivedPerSecond float64 `json:"meanBytesReceivedPerSecond"`
+	PeakBytesReceivedPerSecond float64 `json:"peakBytesReceivedPerSecond"`
+	MeanBytesSentPerSecond     float64 `json:"meanBytesSentPerSecond"`
+	PeakBytesSentPerSecond     float64 `json:"peakBytesSentPerSecond"`
+	TotalBytesReceived         uint64  `json:"totalBytesReceived"`
+	TotalBytesSent             uint64  `json:"totalBytesSent"`
+	AvgResponseBytes           float64 `json:"avgResponseBytes"`
+}
+
+type Throughput struct {
+	RequestsPerSecond      float64 `json:"requestsPerSecond"`
+	BytesReceivedPerSecond float64 `json:"bytesReceivedPerSecond"`
+	BytesSentPerSecond     float64 `json:"bytesSentPerSecond"`
+	AvgResponseBytes       float64 `json:"avgResponseBytes"`
 }
 
 type Latencies struct {
@@ -25,16 +56,45 @@ type Latencies struct {
 	P90Ms float64 `json:"p90Ms"`
 	P95Ms float64 `json:"p95Ms"`
 	P99Ms float64 `json:"p99Ms"`
+	// Unreported marks percentiles the tool didn't report, eg. p(99) in a
+	// default k6 summary. They are left out of published metrics.
+	Unreported Percentiles `json:"-"`
+}
+
+// Percentiles is a set of latency percentiles.
+type Percentiles uint8
+
+const (
+	Percentile50 Percentiles = 1 << iota
+	Percentile75
+	Percentile90
+	Percentile95
+	Percentile99
+
+	AllPercentiles = Percentile50 | Percentile75 | Percentile90 | Percentile95 | Percentile99
+)
+
+// LatencyBreakdown splits the elapsed time of requests, to tell network
+// slowness from backend slowness. Server is the time to first byte.
+type LatencyBreakdown struct {
+	Connect *Latencies `json:"connect"`
+	Server  *Latencies `json:"server"`
+	Idle    *Latencies `json:"idle"`
 }
 
 type MetricDataPoint struct {
 	Label                string `json:"label"`
+	Generator            string `json:"generator"`
 	Requests             uint64 `json:"requests"`
 	Failures             uint64 `json:"failures"`
 	VirtualUsers         uint64 `json:"virtualUsers"`
 	TimeStamp            uint64 `json:"timeStamp"`
 	TimeAggregationLevel TimeAggregationLevel
-	Latencies            *Latencies `json:"latencies"`
+	Latencies            *Latencies        `json:"latencies"`
+	Throughput           *Throughput       `json:"throughput"`
+	LatencyBreakdown     *LatencyBreakdown `json:"latencyBreakdown"`
+	Apdex                *Apdex            `json:"apdex"`
+	Transaction          bool              `json:"transaction"`
 }
 
 type UngroupedMetricDataPoint struct {
@@ -44,6 +104,30 @@ type UngroupedMetricDataPoint struct {
 	TimeStamp    uint64
 	Latency      uint64
 	Label        string
+	ResponseCode string
+	DataType     string
+	ThreadName   string
+	Generator    string
+	// FailureMessage describes why a failed request failed, when available.
+	FailureMessage string
+	// AssertionName is the check or assertion that failed, when available.
+	AssertionName string
+	// BytesReceived and BytesSent are zero when the format doesn't report
+	// them. Rows with only bytes and no requests carry bandwidth reported
+	// separately from requests, eg. k6 data_received.
+	BytesReceived uint64
+	BytesSent     uint64
+	// HasTimings is set when the format reports how the latency breaks down
+	// into connect time, time to first byte and idle time.
+	HasTimings    bool
+	ConnectTime   uint64
+	ServerLatency uint64
+	IdleTime      uint64
+	// ApdexThresholdMs is zero unless an Apdex threshold applies to the label.
+	ApdexThresholdMs uint64
+	// Transaction marks rows that group other requests, eg. a Gatling group.
+	// They are reported by label but left out of overall totals.
+	Transaction bool
 }
 
 var possibleTsFormats = []string{
@@ -56,6 +140,17 @@ var possibleTsFormats = []string{
 }
 
 func ParseTimeStampMillis(timeStamp string) uint64 {
+	parsed, ok := tryParseTimeStampMillis(timeStamp)
+	if ok {
+		return parsed
+	}
+
+	sentry.CaptureMessage("unable to parse timestamp: " + timeStamp)
+	log.Fatalf("unable to parse timestamp: %s", timeStamp)
+	return 0
+}
+
+func tryParseTimeStampMillis(timeStamp string) (uint64, bool) {
 	parsed, err := strconv.ParseUint(timeStamp, 10, 64)
 
 	if err == nil {
@@ -63,17 +158,15 @@ func ParseTimeStampMillis(timeStamp string) uint64 {
 		if parsed < 10000000000 {
 			parsed = parsed * 1000
 		}
-		return parsed
+		return parsed, true
 	}
 
 	for _, tsFormat := range possibleTsFormats {
 		parsed, err := time.ParseInLocation(tsFormat, timeStamp, time.Local)
 		if err == nil {
-			return uint64(parsed.UnixMilli())
+			return uint64(parsed.UnixMilli()), true
 		}
 	}
 
-	sentry.CaptureMessage("unable to parse timestamp: " + timeStamp)
-	log.Fatalf("unable to parse timestamp: %s", timeStamp)
-	return 0
+	return 0, false
 }
@@ -0,0 +1,98 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+func buildDefaultColumnIndicesAb() map[string]int {
+	// starttime	seconds	ctime	dtime	ttime	wait
+	// Thu Mar 17 10:00:12 2022	1647511212	0	2	2	2
+	return map[string]int{
+		"seconds": -1,
+		"ctime":   -1,
+		"ttime":   -1,
+		"wait":    -1,
+	}
+}
+
+func BuildColumnIndicesAb(row []string) (map[string]int, error) {
+	indices := buildDefaultColumnIndicesAb()
+	for i, header := range row {
+		if _, ok := indices[header]; ok {
+			indices[header] = i
+		}
+	}
+
+	missing := []string{}
+
+	for column, index := range indices {
+		if index == -1 {
+			missing = append(missing, column)
+		}
+	}
+
+	if len(missing) > 0 {
+		return nil, errors.New("missing column(s): " + strings.Join(missing, ", "))
+	}
+
+	return indices, nil
+}
+
+// AbRequest is a row of ab -g output. The start time is in whole seconds and
+// times are in milliseconds.
+type AbRequest struct {
+	StartedAt   uint64
+	ConnectTime uint64
+	TotalTime   uint64
+	WaitTime    uint64
+}
+
+func ParseAbRow(row []string, indices map[string]int) (AbRequest, error) {
+	var (
+		request AbRequest
+		err     error
+	)
+
+	values := map[string]*uint64{
+		"seconds": &request.StartedAt,
+		"ctime":   &request.ConnectTime,
+		"ttime":   &request.TotalTime,
+		"wait":    &request.WaitTime,
+	}
+	for column, value := range values {
+		if *value, err = strconv.ParseUint(strings.TrimSpace(row[indices[column]]), 10, 64); err != nil {
+			return AbRequest{}, fmt.Errorf("failed to parse %s: %v", column, err)
+		}
+	}
+	request.StartedAt *= 1000
+
+	return request, nil
+}
+
+// TranslateAbRow maps a request to a row. ab doesn't write status codes to
+// the gnuplot file, so requests can't be told apart as failures.
+func TranslateAbRow(request AbRequest, label string) UngroupedMetricDataPoint {
+	return UngroupedMetricDataPoint{
+		Requests:      1,
+		TimeStamp:     request.StartedAt,
+		Latency:       request.TotalTime,
+		Label:         label,
+		HasTimings:    true,
+		ConnectTime:   request.ConnectTime,
+		ServerLatency: request.WaitTime,
+	}
+}
+
+func TranslateAbSample(request AbRequest, label string) LingoSample {
+	return LingoSample{
+		TimeStamp: request.StartedAt,
+		Label:     label,
+		Elapsed:   request.TotalTime,
+		Success:   true,
+		Latency:   request.WaitTime,
+		Connect:   request.ConnectTime,
+	}
+}
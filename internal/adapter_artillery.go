@@ -0,0 +1,168 @@
+package internal
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ArtillerySummary struct {
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Count  float64 `json:"count"`
+	Mean   float64 `json:"mean"`
+	P50    float64 `json:"p50"`
+	Median float64 `json:"median"`
+	P75    float64 `json:"p75"`
+	P90    float64 `json:"p90"`
+	P95    float64 `json:"p95"`
+	P99    float64 `json:"p99"`
+}
+
+// ArtilleryPeriod holds the metrics of an intermediate period, or of the
+// whole run for the aggregate. Period is the start of the period in
+// milliseconds, written as a string by recent Artillery versions.
+type ArtilleryPeriod struct {
+	Counters      map[string]float64          `json:"counters"`
+	Rates         map[string]float64          `json:"rates"`
+	Summaries     map[string]ArtillerySummary `json:"summaries"`
+	Period        json.Number                 `json:"period"`
+	FirstMetricAt uint64                      `json:"firstMetricAt"`
+	LastMetricAt  uint64                      `json:"lastMetricAt"`
+}
+
+type ArtilleryReport struct {
+	Aggregate    ArtilleryPeriod   `json:"aggregate"`
+	Intermediate []ArtilleryPeriod `json:"intermediate"`
+}
+
+// Metrics of the metrics-by-endpoint plugin, the only source of labels.
+const (
+	artilleryEndpointPrefix       = "plugins.metrics-by-endpoint."
+	artilleryEndpointResponseTime = artilleryEndpointPrefix + "response_time."
+)
+
+// artilleryDefaultPeriod is Artillery's reporting interval, used when a report
+// has a single intermediate period or irregular gaps between periods.
+const artilleryDefaultPeriod = 10 * time.Second
+
+func (p ArtilleryPeriod) StartedAt() uint64 {
+	if started, err := strconv.ParseUint(p.Period.String(), 10, 64); err == nil {
+		return started
+	}
+	return p.FirstMetricAt
+}
+
+// Labels returns the endpoints reported by the metrics-by-endpoint plugin.
+func (p ArtilleryPeriod) Labels() []string {
+	var labels []string
+	for key := range p.Summaries {
+		if strings.HasPrefix(key, artilleryEndpointResponseTime) {
+			labels = append(labels, key[len(artilleryEndpointResponseTime):])
+		}
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// Requests counts responses by status code plus errors without a response.
+// Responses with a 4xx or 5xx status and errors are failures.
+func (p ArtilleryPeriod) Requests(label string) (uint64, uint64) {
+	codesPrefix, errorsPrefix := "http.codes.", "errors."
+	if label != "" {
+		codesPrefix = artilleryEndpointPrefix + label + ".codes."
+		errorsPrefix = artilleryEndpointPrefix + label + ".errors."
+	}
+
+	var requests, failures float64
+	for key, value := range p.Counters {
+		if strings.HasPrefix(key, codesPrefix) {
+			requests += value
+			if code, err := strconv.Atoi(key[len(codesPrefix):]); err == nil && code >= 400 {
+				failures += value
+			}
+		} else if strings.HasPrefix(key, errorsPrefix) {
+			requests += value
+			failures += value
+		}
+	}
+
+	return uint64(requests), uint64(failures)
+}
+
+func (p ArtilleryPeriod) Latencies(label string) *Latencies {
+	key := "http.response_time"
+	if label != "" {
+		key = artilleryEndpointResponseTime + label
+	}
+
+	summary := p.Summaries[key]
+	p50 := summary.P50
+	if p50 == 0 {
+		p50 = summary.Median
+	}
+
+	latencies := &Latencies{
+		AvgMs: summary.Mean,
+		MinMs: summary.Min,
+		MaxMs: summary.Max,
+		P50Ms: p50,
+		P75Ms: summary.P75,
+		P90Ms: summary.P90,
+		P95Ms: summary.P95,
+		P99Ms: summary.P99,
+	}
+	roundLatencies(latencies)
+	return latencies
+}
+
+// VirtualUsers estimates the mean number of active virtual users from the
+// sessions completed in the period and their mean length, as Artillery only
+// counts created and completed users.
+func (p ArtilleryPeriod) VirtualUsers(duration time.Duration) uint64 {
+	sessions := p.Summaries["vusers.session_length"]
+	if sessions.Count == 0 || duration <= 0 {
+		return 0
+	}
+	return uint64(math.Ceil(sessions.Count * sessions.Mean / float64(duration.Milliseconds())))
+}
+
+// TranslateArtilleryPeriod maps a period to an overall bucket followed by a
+// bucket per endpoint. Percentiles are kept as reported, they are published
+// untouched at the period's own level and only averaged when rolled up.
+func TranslateArtilleryPeriod(period ArtilleryPeriod, duration time.Duration) []AggregatedBucket {
+	requests, failures := period.Requests("")
+	buckets := []AggregatedBucket{{
+		TimeStamp:     period.StartedAt(),
+		Duration:      duration,
+		Requests:      requests,
+		Failures:      failures,
+		VirtualUsers:  period.VirtualUsers(duration),
+		BytesReceived: uint64(period.Counters["http.downloaded_bytes"]),
+		Latencies:     period.Latencies(""),
+	}}
+
+	for _, label := range period.Labels() {
+		requests, failures := period.Requests(label)
+		buckets = append(buckets, AggregatedBucket{
+			Label:     label,
+			TimeStamp: period.StartedAt(),
+			Duration:  duration,
+			Requests:  requests,
+			Failures:  failures,
+			Latencies: period.Latencies(label),
+		})
+	}
+
+	return buckets
+}
+
+// applyArtilleryAggregate replaces rolled up totals and latencies with the
+// ones Artillery calculated for the whole run, which are exact.
+func applyArtilleryAggregate(summary *MetricSummary, aggregate ArtilleryPeriod, label string) {
+	summary.TotalRequests, summary.TotalFailures = aggregate.Requests(label)
+	summary.Latencies = aggregate.Latencies(label)
+}
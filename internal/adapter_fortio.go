@@ -0,0 +1,144 @@
+package internal
+
+import (
+	"strconv"
+	"time"
+)
+
+// FortioHistogram is a Fortio histogram. Values are in seconds for
+// durations and bytes for sizes.
+type FortioHistogram struct {
+	Count       uint64
+	Min         float64
+	Max         float64
+	Sum         float64
+	Avg         float64
+	Data        []FortioHistogramBin
+	Percentiles []FortioPercentile
+}
+
+type FortioHistogramBin struct {
+	Start float64
+	End   float64
+	Count uint64
+}
+
+type FortioPercentile struct {
+	Percentile float64
+	Value      float64
+}
+
+// FortioResult is the JSON result of fortio load -json, for the HTTP and gRPC
+// runners. ActualDuration is in nanoseconds.
+type FortioResult struct {
+	RunType           string
+	Labels            string
+	StartTime         time.Time
+	ActualQPS         float64
+	ActualDuration    time.Duration
+	NumThreads        uint64
+	DurationHistogram FortioHistogram
+	RetCodes          map[string]uint64
+	Sizes             *FortioHistogram
+	URL               string
+	Destination       string
+}
+
+// FortioLabel is the URL or gRPC destination that was load tested.
+func FortioLabel(result FortioResult) string {
+	if result.URL != "" {
+		return result.URL
+	}
+	return result.Destination
+}
+
+// fortioFailed tells whether requests with a return code failed. HTTP codes
+// outside 2xx and 3xx fail, including -1 for socket errors, and gRPC health
+// statuses other than SERVING.
+func fortioFailed(code string) bool {
+	if status, err := strconv.Atoi(code); err == nil {
+		return status < 200 || status >= 400
+	}
+	return code != "SERVING" && code != "OK"
+}
+
+// TranslateFortioResult maps the result to a bucket spanning the run, with the
+// duration histogram so percentiles Fortio didn't report can be computed.
+func TranslateFortioResult(result FortioResult, label string) AggregatedBucket {
+	histogram := result.DurationHistogram
+	bucket := AggregatedBucket{
+		Label:        label,
+		TimeStamp:    uint64(result.StartTime.UnixMilli()),
+		Duration:     result.ActualDuration.Round(time.Millisecond),
+		Requests:     histogram.Count,
+		VirtualUsers: result.NumThreads,
+		Latencies: &Latencies{
+			AvgMs: histogram.Avg * 1000,
+			MinMs: histogram.Min * 1000,
+			MaxMs: histogram.Max * 1000,
+		},
+	}
+
+	for _, bin := range histogram.Data {
+		bucket.Histogram = append(bucket.Histogram, HistogramBin{UpperBoundMs: bin.End * 1000, Count: bin.Count})
+	}
+
+	for code, count := range result.RetCodes {
+		if fortioFailed(code) {
+			bucket.Failures += count
+		}
+	}
+
+	if result.Sizes != nil {
+		bucket.BytesReceived = uint64(result.Sizes.Sum)
+	}
+
+	return bucket
+}
+
+// applyFortioPercentiles overrides percentiles computed from the histogram
+// with the ones Fortio reported, which are interpolated within bins.
+func applyFortioPercentiles(latencies *Latencies, percentiles []FortioPercentile) {
+	for _, percentile := range percentiles {
+		value := percentile.Value * 1000
+		switch percentile.Percentile {
+		case 50:
+			latencies.P50Ms = value
+		case 75:
+			latencies.P75Ms = value
+		case 90:
+			latencies.P90Ms = value
+		case 95:
+			latencies.P95Ms = value
+		case 99:
+			latencies.P99Ms = value
+		}
+	}
+	roundLatencies(latencies)
+}
+
+// TranslateFortioSummary summarizes the run from its histogram. Fortio
+// doesn't report intervals, so there are no peaks.
+func TranslateFortioSummary(result FortioResult, label string) SummaryData {
+	bucket := TranslateFortioResult(result, label)
+	summary, summaryByLabel := SummarizeAggregatedBuckets([]AggregatedBucket{bucket})
+
+	applyFortioPercentiles(summary.Latencies, result.DurationHistogram.Percentiles)
+	summary.Throughput.MeanRequestsPerSecond = result.ActualQPS
+	summary.Throughput.PeakRequestsPerSecond = 0
+	summary.Throughput.PeakBytesReceivedPerSecond = 0
+	summary.Throughput.PeakBytesSentPerSecond = 0
+
+	labelSummary := summaryByLabel[label]
+	applyFortioPercentiles(labelSummary.Latencies, result.DurationHistogram.Percentiles)
+	labelSummary.Throughput = nil
+	summaryByLabel[label] = labelSummary
+
+	startedAt, stoppedAt := AggregatedBucketsRange([]AggregatedBucket{bucket})
+	return SummaryData{
+		Summary:        summary,
+		SummaryByLabel: summaryByLabel,
+		StartedAt:      startedAt,
+		StoppedAt:      stoppedAt,
+	}
+}
@@ -1,24 +1,162 @@
 package internal
 
-func TranslateGatlingRow(row []string) UngroupedMetricDataPoint {
-	// row[0] = REQUEST
-	// row[1] = ""
-	// row[2] = GET low latency
-	// row[3] = 1647457744634
-	// row[4] = 1647457744825
-	// row[5] = OK
+import (
+	"strconv"
+	"strings"
+)
 
-	failures := 0
-	if row[5] != "OK" {
-		failures = 1
+// Gatling moved columns around between versions, eg. 3.4 dropped the user id
+// from REQUEST and GROUP records. Time stamps are the only columns that can be
+// told apart reliably, so the other columns are located relative to them.
+//
+// REQUEST	[scenario	userId]	groups	name	start	end	status	message
+// GROUP	[userId]	groups	start	end	cumulatedResponseTime	status
+// USER	scenario	START|END	timestamp
+// USER	scenario	userId	START|END	start	end
+// RUN	simulationClass	simulationId	start	description	version
+// ERROR	message	timestamp
+
+func isGatlingTimeStamp(column string) bool {
+	parsed, err := strconv.ParseUint(column, 10, 64)
+	// epoch milliseconds, filters out user ids and cumulated response times
+	return err == nil && parsed >= 1000000000000
+}
+
+// gatlingTimeColumns returns the first and last column of the first run of
+// time stamps in the record.
+func gatlingTimeColumns(row []string) (int, int, bool) {
+	for i, column := range row {
+		if !isGatlingTimeStamp(column) {
+			continue
+		}
+
+		end := i
+		for end+1 < len(row) && isGatlingTimeStamp(row[end+1]) {
+			end++
+		}
+
+		if end == i {
+			return 0, 0, false
+		}
+		return i, end, true
+	}
+
+	return 0, 0, false
+}
+
+func gatlingGroupName(groups string) string {
+	return strings.Join(strings.Split(groups, ","), " / ")
+}
+
+func TranslateGatlingRow(row []string) (UngroupedMetricDataPoint, bool) {
+	// REQUEST		GET low latency	1647457744634	1647457744825	OK
+	start, end, ok := gatlingTimeColumns(row)
+	if !ok || start < 2 || end+1 >= len(row) {
+		return UngroupedMetricDataPoint{}, false
 	}
 
-	return UngroupedMetricDataPoint{
+	startedAt := ParseTimeStampMillis(row[start])
+	stoppedAt := ParseTimeStampMillis(row[end])
+
+	parsed := UngroupedMetricDataPoint{
 		Requests:     1,
-		Failures:     uint64(failures),
-		TimeStamp:    ParseTimeStampMillis(row[3]) / 1000,
-		Latency:      uint64(ParseTimeStampMillis(row[4]) - ParseTimeStampMillis(row[3])),
-		Label:        row[2],
+		TimeStamp:    startedAt,
+		Latency:      stoppedAt - startedAt,
+		Label:        row[start-1],
 		VirtualUsers: 0,
 	}
+
+	if row[end+1] != "OK" {
+		parsed.Failures = 1
+		if end+2 < len(row) {
+			parsed.FailureMessage = row[end+2]
+			// check failures read like "status.find.is(200), but actually found 500"
+			if index := strings.Index(parsed.FailureMessage, ", but actually"); index != -1 {
+				parsed.AssertionName = parsed.FailureMessage[:index]
+			}
+		}
+	}
+
+	return parsed, true
+}
+
+func TranslateGatlingGroupRow(row []string) (UngroupedMetricDataPoint, bool) {
+	// GROUP	checkout,payment	1647457744634	1647457745825	380	OK
+	start, end, ok := gatlingTimeColumns(row)
+	if !ok || start < 2 || end+2 >= len(row) {
+		return UngroupedMetricDataPoint{}, false
+	}
+
+	// cumulated response time excludes pauses between the group's requests
+	cumulated, err := strconv.ParseUint(row[end+1], 10, 64)
+	if err != nil {
+		return UngroupedMetricDataPoint{}, false
+	}
+
+	parsed := UngroupedMetricDataPoint{
+		Requests:    1,
+		TimeStamp:   ParseTimeStampMillis(row[start]),
+		Latency:     cumulated,
+		Label:       gatlingGroupName(row[start-1]),
+		Transaction: true,
+	}
+
+	if row[end+2] != "OK" {
+		parsed.Failures = 1
+	}
+
+	return parsed, true
+}
+
+func TranslateGatlingRunRow(row []string) (*RunMetadata, bool) {
+	// RUN	computerdatabase.BasicSimulation	basicsimulation	1647457743000	 	3.7.6
+	for i, column := range row {
+		if i >= 2 && isGatlingTimeStamp(column) {
+			return &RunMetadata{
+				Name:      row[1],
+				StartedAt: ParseTimeStampMillis(column),
+			}, true
+		}
+	}
+
+	return nil, false
+}
+
+func TranslateGatlingUserRow(row []string) (UserEvent, bool) {
+	// USER	scenario	START	1647457744634
+	// USER	scenario	END	1647457745825
+	// older 3.x versions include the user id and both time stamps:
+	// USER	scenario	1	END	1647457744634	1647457745825
+	for i, column := range row {
+		if column != "START" && column != "END" || i+1 >= len(row) {
+			continue
+		}
+
+		timeStamp := row[i+1]
+		if column == "END" && i+2 < len(row) && isGatlingTimeStamp(row[i+2]) {
+			timeStamp = row[i+2]
+		}
+
+		parsed, ok := tryParseTimeStampMillis(timeStamp)
+		if !ok {
+			return UserEvent{}, false
+		}
+
+		return UserEvent{TimeStamp: parsed, Started: column == "START"}, true
+	}
+
+	return UserEvent{}, false
+}
+
+func TranslateGatlingErrorRow(row []string) (ErrorEvent, bool) {
+	// ERROR	Failed to build request: No attribute named 'id' is defined	1647457744634
+	if len(row) < 3 || !isGatlingTimeStamp(row[len(row)-1]) {
+		return ErrorEvent{}, false
+	}
+
+	return ErrorEvent{
+		TimeStamp: ParseTimeStampMillis(row[len(row)-1]),
+		Category:  ErrorCategoryMessage,
+		Value:     NormalizeFailureMessage(row[1]),
+	}, true
 }
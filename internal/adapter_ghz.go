@@ -0,0 +1,142 @@
+package internal
+
+import (
+	"strings"
+	"time"
+)
+
+type GhzOptions struct {
+	Call        string `json:"call"`
+	Host        string `json:"host"`
+	Concurrency uint64 `json:"concurrency"`
+}
+
+type GhzLatency struct {
+	Percentage float64       `json:"percentage"`
+	Latency    time.Duration `json:"latency"`
+}
+
+// GhzDetail is a single call. Latency is in nanoseconds.
+type GhzDetail struct {
+	Timestamp time.Time     `json:"timestamp"`
+	Latency   time.Duration `json:"latency"`
+	Error     string        `json:"error"`
+	Status    string        `json:"status"`
+}
+
+// GhzReport is the JSON report of ghz -O json. Durations are in nanoseconds.
+type GhzReport struct {
+	Date                   time.Time         `json:"date"`
+	Options                GhzOptions        `json:"options"`
+	Count                  uint64            `json:"count"`
+	Total                  time.Duration     `json:"total"`
+	Average                time.Duration     `json:"average"`
+	Fastest                time.Duration     `json:"fastest"`
+	Slowest                time.Duration     `json:"slowest"`
+	Rps                    float64           `json:"rps"`
+	ErrorDistribution      map[string]uint64 `json:"errorDistribution"`
+	StatusCodeDistribution map[string]uint64 `json:"statusCodeDistribution"`
+	LatencyDistribution    []GhzLatency      `json:"latencyDistribution"`
+	Details                []GhzDetail       `json:"details"`
+}
+
+// ghzStatusOK is the gRPC status of successful calls, every other status is
+// a failure.
+const ghzStatusOK = "OK"
+
+// GhzLabel is the called method, eg. helloworld.Greeter/SayHello.
+func GhzLabel(options GhzOptions) string {
+	call := options.Call
+	if index := strings.LastIndex(call, "."); index != -1 && !strings.Contains(call, "/") {
+		call = call[:index] + "/" + call[index+1:]
+	}
+	return call
+}
+
+func durationMillis(duration time.Duration) float64 {
+	return float64(duration.Microseconds()) / 1000
+}
+
+// TranslateGhzDetail maps a call to a row, with the gRPC status as response
+// code so failures are classified by status and error message.
+func TranslateGhzDetail(detail GhzDetail, label string, virtualUsers uint64) UngroupedMetricDataPoint {
+	parsed := UngroupedMetricDataPoint{
+		Requests:     1,
+		VirtualUsers: virtualUsers,
+		TimeStamp:    uint64(detail.Timestamp.UnixMilli()),
+		Latency:      uint64(detail.Latency.Milliseconds()),
+		Label:        label,
+		ResponseCode: detail.Status,
+	}
+
+	if detail.Status != ghzStatusOK {
+		parsed.Failures = 1
+		parsed.FailureMessage = detail.Error
+	}
+
+	return parsed
+}
+
+func TranslateGhzDetailSample(detail GhzDetail, label string, virtualUsers uint64) LingoSample {
+	return LingoSample{
+		TimeStamp:       uint64(detail.Timestamp.UnixMilli()),
+		Label:           label,
+		Elapsed:         uint64(detail.Latency.Milliseconds()),
+		ResponseMessage: detail.Status,
+		Success:         detail.Status == ghzStatusOK,
+		FailureMessage:  detail.Error,
+		AllThreads:      int(virtualUsers),
+	}
+}
+
+// TranslateGhzSummary maps the report totals to a summary, for reports
+// without details.
+func TranslateGhzSummary(report GhzReport) MetricSummary {
+	summary := MetricSummary{
+		Latencies: &Latencies{
+			AvgMs: durationMillis(report.Average),
+			MinMs: durationMillis(report.Fastest),
+			MaxMs: durationMillis(report.Slowest),
+			// cleared for each percentile found in the distribution
+			Unreported: AllPercentiles,
+		},
+		TotalRequests:   report.Count,
+		MaxVirtualUsers: report.Options.Concurrency,
+		Throughput:      &ThroughputSummary{MeanRequestsPerSecond: report.Rps},
+	}
+
+	for _, latency := range report.LatencyDistribution {
+		switch latency.Percentage {
+		case 50:
+			summary.Latencies.P50Ms = durationMillis(latency.Latency)
+			summary.Latencies.Unreported &^= Percentile50
+		case 75:
+			summary.Latencies.P75Ms = durationMillis(latency.Latency)
+			summary.Latencies.Unreported &^= Percentile75
+		case 90:
+			summary.Latencies.P90Ms = durationMillis(latency.Latency)
+			summary.Latencies.Unreported &^= Percentile90
+		case 95:
+			summary.Latencies.P95Ms = durationMillis(latency.Latency)
+			summary.Latencies.Unreported &^= Percentile95
+		case 99:
+			summary.Latencies.P99Ms = durationMillis(latency.Latency)
+			summary.Latencies.Unreported &^= Percentile99
+		}
+	}
+
+	if len(report.StatusCodeDistribution) > 0 {
+		for status, count := range report.StatusCodeDistribution {
+			if status != ghzStatusOK {
+				summary.TotalFailures += count
+			}
+		}
+	} else {
+		for _, count := range report.ErrorDistribution {
+			summary.TotalFailures += count
+		}
+	}
+
+	roundLatencies(summary.Latencies)
+	return summary
+}
@@ -0,0 +1,115 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+func buildDefaultColumnIndicesHey() map[string]int {
+	// response-time,DNS+dialup,DNS,Request-write,Response-delay,Response-read,status-code,offset
+	// 0.0218,0.0120,0.0011,0.0000,0.0094,0.0003,200,0.0010
+	return map[string]int{
+		"response-time":  -1,
+		"DNS+dialup":     -1,
+		"Response-delay": -1,
+		"status-code":    -1,
+		"offset":         -1,
+	}
+}
+
+func BuildColumnIndicesHey(row []string) (map[string]int, error) {
+	indices := buildDefaultColumnIndicesHey()
+	for i, header := range row {
+		if _, ok := indices[header]; ok {
+			indices[header] = i
+		}
+	}
+
+	missing := []string{}
+
+	for column, index := range indices {
+		if index == -1 {
+			missing = append(missing, column)
+		}
+	}
+
+	if len(missing) > 0 {
+		return nil, errors.New("missing column(s): " + strings.Join(missing, ", "))
+	}
+
+	return indices, nil
+}
+
+// HeyRequest is a row of hey -o csv. Times are in seconds, and the offset is
+// from the start of the run.
+type HeyRequest struct {
+	ResponseTime  float64
+	DialUp        float64
+	ResponseDelay float64
+	StatusCode    int
+	Offset        float64
+}
+
+func ParseHeyRow(row []string, indices map[string]int) (HeyRequest, error) {
+	var (
+		request HeyRequest
+		err     error
+	)
+
+	floats := map[string]*float64{
+		"response-time":  &request.ResponseTime,
+		"DNS+dialup":     &request.DialUp,
+		"Response-delay": &request.ResponseDelay,
+		"offset":         &request.Offset,
+	}
+	for column, value := range floats {
+		if *value, err = strconv.ParseFloat(row[indices[column]], 64); err != nil {
+			return HeyRequest{}, fmt.Errorf("failed to parse %s: %v", column, err)
+		}
+	}
+
+	if request.StatusCode, err = strconv.Atoi(row[indices["status-code"]]); err != nil {
+		return HeyRequest{}, fmt.Errorf("failed to parse status-code: %v", err)
+	}
+
+	return request, nil
+}
+
+func secondsToMillis(seconds float64) uint64 {
+	return uint64(seconds*1000 + 0.5)
+}
+
+// TranslateHeyRow stamps a request with the run start in milliseconds. hey
+// only writes requests that got a response, so failures are 4xx and 5xx.
+func TranslateHeyRow(request HeyRequest, startedAt uint64, label string) UngroupedMetricDataPoint {
+	parsed := UngroupedMetricDataPoint{
+		Requests:      1,
+		TimeStamp:     startedAt + secondsToMillis(request.Offset),
+		Latency:       secondsToMillis(request.ResponseTime),
+		Label:         label,
+		ResponseCode:  strconv.Itoa(request.StatusCode),
+		HasTimings:    true,
+		ConnectTime:   secondsToMillis(request.DialUp),
+		ServerLatency: secondsToMillis(request.ResponseDelay),
+	}
+
+	if request.StatusCode >= 400 {
+		parsed.Failures = 1
+	}
+
+	return parsed
+}
+
+func TranslateHeySample(request HeyRequest, startedAt uint64, label string) LingoSample {
+	return LingoSample{
+		TimeStamp:    startedAt + secondsToMillis(request.Offset),
+		Label:        label,
+		Elapsed:      secondsToMillis(request.ResponseTime),
+		ResponseCode: request.StatusCode,
+		Success:      request.StatusCode < 400,
+		Latency:      secondsToMillis(request.ResponseDelay),
+		Connect:      secondsToMillis(request.DialUp),
+	}
+}
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"encoding/xml"
 	"errors"
 	"log"
 	"strconv"
@@ -18,11 +19,37 @@ type ColumnIndices struct {
 	TimeStampFound  bool
 	Label           uint32
 	LabelFound      bool
+	// optional columns used for filtering
+	ResponseCode      uint32
+	ResponseCodeFound bool
+	DataType          uint32
+	DataTypeFound     bool
+	ThreadName        uint32
+	ThreadNameFound   bool
+	// optional columns used for error breakdowns
+	FailureMessage      uint32
+	FailureMessageFound bool
+	// optional columns used to recognize transaction controller samples
+	ResponseMessage      uint32
+	ResponseMessageFound bool
+	URL                  uint32
+	URLFound             bool
+	// optional columns used for latency breakdowns
+	Latency       uint32
+	LatencyFound  bool
+	Connect       uint32
+	ConnectFound  bool
+	IdleTime      uint32
+	IdleTimeFound bool
+	// optional columns used for bandwidth
+	Bytes          uint32
+	BytesFound     bool
+	SentBytes      uint32
+	SentBytesFound bool
 }
 
 func buildDefaultColumnIndices() map[string]int {
 	// timeStamp,elapsed,label,responseCode,responseMessage,threadName,dataType,success,failureMessage,bytes,sentBytes,grpThreads,allThreads,URL,Latency,IdleTime,Connect
-	// TODO(bobsin): add support for Latency
 	return map[string]int{
 		"timeStamp":       -1,
 		"elapsed":         -1,
@@ -84,6 +111,39 @@ func BuildColumnIndices(row []string) (*ColumnIndices, error) {
 		case "label":
 			indices.Label = uint32(i)
 			indices.LabelFound = true
+		case "responseCode":
+			indices.ResponseCode = uint32(i)
+			indices.ResponseCodeFound = true
+		case "dataType":
+			indices.DataType = uint32(i)
+			indices.DataTypeFound = true
+		case "threadName":
+			indices.ThreadName = uint32(i)
+			indices.ThreadNameFound = true
+		case "failureMessage":
+			indices.FailureMessage = uint32(i)
+			indices.FailureMessageFound = true
+		case "responseMessage":
+			indices.ResponseMessage = uint32(i)
+			indices.ResponseMessageFound = true
+		case "URL":
+			indices.URL = uint32(i)
+			indices.URLFound = true
+		case "Latency":
+			indices.Latency = uint32(i)
+			indices.LatencyFound = true
+		case "Connect":
+			indices.Connect = uint32(i)
+			indices.ConnectFound = true
+		case "IdleTime":
+			indices.IdleTime = uint32(i)
+			indices.IdleTimeFound = true
+		case "bytes":
+			indices.Bytes = uint32(i)
+			indices.BytesFound = true
+		case "sentBytes":
+			indices.SentBytes = uint32(i)
+			indices.SentBytesFound = true
 		}
 	}
 
@@ -143,6 +203,15 @@ func TranslateJmeterRowSample(row []string, indices map[string]int) LingoSample
 		log.Println("error parsing grpThreads", err)
 	}
 
+	// Latency is optional, older configurations don't save it
+	var latency uint64
+	if index, ok := indices["Latency"]; ok && index < len(row) {
+		latency, err = strconv.ParseUint(row[index], 10, 64)
+		if err != nil {
+			log.Println("error parsing Latency", err)
+		}
+	}
+
 	idleTime, err := strconv.ParseUint(row[indices["IdleTime"]], 10, 64)
 	if err != nil {
 		log.Println("error parsing IdleTime", err)
@@ -168,11 +237,26 @@ func TranslateJmeterRowSample(row []string, indices map[string]int) LingoSample
 		GrpThreads:      int(grpThreads),
 		AllThreads:      int(allThreads),
 		URL:             row[indices["URL"]],
+		Latency:         latency,
 		IdleTime:        idleTime,
 		Connect:         connect,
+		Transaction:     IsJmeterTransaction(row[indices["responseMessage"]], row[indices["dataType"]], row[indices["URL"]]),
 	}
 }
 
+const jmeterTransactionMessage = "Number of samples in transaction"
+
+// IsJmeterTransaction recognizes samples written by Transaction Controllers.
+// Without "Generate parent sample" the response message counts the samples in
+// the transaction, otherwise the parent sample has no data type or URL.
+func IsJmeterTransaction(responseMessage string, dataType string, url string) bool {
+	if strings.HasPrefix(responseMessage, jmeterTransactionMessage) {
+		return true
+	}
+
+	return dataType == "" && url == "null"
+}
+
 func TranslateJmeterRow(row []string, indices *ColumnIndices) UngroupedMetricDataPoint {
 	var (
 		failures uint64
@@ -200,9 +284,103 @@ func TranslateJmeterRow(row []string, indices *ColumnIndices) UngroupedMetricDat
 		Requests:     1,
 		Failures:     failures,
 		VirtualUsers: virtualUsers,
-		TimeStamp:    ParseTimeStampMillis(row[indices.TimeStamp]) / 1000,
+		TimeStamp:    ParseTimeStampMillis(row[indices.TimeStamp]),
 		Latency:      latency,
 	}
 
+	if indices.ResponseCodeFound {
+		parsed.ResponseCode = row[indices.ResponseCode]
+	}
+	if indices.DataTypeFound {
+		parsed.DataType = row[indices.DataType]
+	}
+	if indices.ThreadNameFound {
+		parsed.ThreadName = row[indices.ThreadName]
+	}
+	if indices.FailureMessageFound {
+		parsed.FailureMessage = row[indices.FailureMessage]
+	}
+	if indices.LatencyFound {
+		parsed.HasTimings = true
+		parsed.ServerLatency = parseOptionalUint(row[indices.Latency])
+		if indices.ConnectFound {
+			parsed.ConnectTime = parseOptionalUint(row[indices.Connect])
+		}
+		if indices.IdleTimeFound {
+			parsed.IdleTime = parseOptionalUint(row[indices.IdleTime])
+		}
+	}
+	if indices.BytesFound {
+		parsed.BytesReceived = parseOptionalUint(row[indices.Bytes])
+	}
+	if indices.SentBytesFound {
+		parsed.BytesSent = parseOptionalUint(row[indices.SentBytes])
+	}
+
+	var responseMessage, url string
+	if indices.ResponseMessageFound {
+		responseMessage = row[indices.ResponseMessage]
+	}
+	// parent samples are recognized by an empty data type and a "null" URL,
+	// which needs both columns
+	if indices.URLFound && indices.DataTypeFound {
+		url = row[indices.URL]
+	}
+	parsed.Transaction = IsJmeterTransaction(responseMessage, parsed.DataType, url)
+
+	return parsed
+}
+
+// parseOptionalUint treats missing or malformed values in optional columns as 0.
+func parseOptionalUint(value string) uint64 {
+	parsed, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return parsed
+}
+
+type JmeterXmlAssertion struct {
+	Name           string `xml:"name"`
+	Failure        bool   `xml:"failure"`
+	Error          bool   `xml:"error"`
+	FailureMessage string `xml:"failureMessage"`
+}
+
+// TranslateJmeterXmlSample reads the attributes of a sample or httpSample
+// element, eg. <httpSample t="120" lt="80" ct="10" ts="1647453612000" s="true" lb="home" .../>.
+func TranslateJmeterXmlSample(attrs []xml.Attr) UngroupedMetricDataPoint {
+	values := make(map[string]string, len(attrs))
+	for _, attr := range attrs {
+		values[attr.Name.Local] = attr.Value
+	}
+
+	parsed := UngroupedMetricDataPoint{
+		Label:         values["lb"],
+		Requests:      1,
+		VirtualUsers:  parseOptionalUint(values["na"]),
+		TimeStamp:     ParseTimeStampMillis(values["ts"]),
+		Latency:       parseOptionalUint(values["t"]),
+		ResponseCode:  values["rc"],
+		DataType:      values["dt"],
+		ThreadName:    values["tn"],
+		BytesReceived: parseOptionalUint(values["by"]),
+		BytesSent:     parseOptionalUint(values["sby"]),
+	}
+
+	if values["s"] != "true" {
+		parsed.Failures = 1
+	}
+
+	if _, ok := values["lt"]; ok {
+		parsed.HasTimings = true
+		parsed.ServerLatency = parseOptionalUint(values["lt"])
+		parsed.ConnectTime = parseOptionalUint(values["ct"])
+		parsed.IdleTime = parseOptionalUint(values["it"])
+	}
+
+	// the URL is a child element, nested samples are recognized by the parser
+	parsed.Transaction = IsJmeterTransaction(values["rm"], values["dt"], "")
+
 	return parsed
 }
@@ -1,6 +1,17 @@
 package internal
 
+import (
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
 type K6MetricTags struct {
+	Check            string `json:"check"`
+	Error            string `json:"error"`
+	ErrorCode        string `json:"error_code"`
 	ExpectedResponse string `json:"expected_response"`
 	Group            string `json:"group"`
 	Method           string `json:"method"`
@@ -15,6 +26,10 @@ type K6MetricData struct {
 	Time  string       `json:"time"`
 	Value float64      `json:"value"`
 	Tags  K6MetricTags `json:"tags"`
+	// only set for Metric records
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Contains string `json:"contains"`
 }
 
 type K6Metric struct {
@@ -30,11 +45,243 @@ func TranslateK6Row(row K6Metric) UngroupedMetricDataPoint {
 	}
 
 	return UngroupedMetricDataPoint{
-		Requests:     1,
-		Failures:     uint64(failures),
-		VirtualUsers: 0,
-		TimeStamp:    ParseTimeStampMillis(row.Data.Time) / 1000,
-		Latency:      uint64(row.Data.Value),
-		Label:        row.Data.Tags.Name,
+		Requests:       1,
+		Failures:       uint64(failures),
+		VirtualUsers:   0,
+		TimeStamp:      ParseTimeStampMillis(row.Data.Time),
+		Latency:        uint64(row.Data.Value),
+		Label:          row.Data.Tags.Name,
+		ResponseCode:   row.Data.Tags.Status,
+		FailureMessage: row.Data.Tags.Error,
+	}
+}
+
+// TranslateK6BandwidthRow turns a data_received or data_sent sample into a row
+// without requests, so bytes are counted towards throughput only.
+func TranslateK6BandwidthRow(row K6Metric, labelBy string) UngroupedMetricDataPoint {
+	parsed := UngroupedMetricDataPoint{
+		TimeStamp: ParseTimeStampMillis(row.Data.Time),
+		Label:     K6Label(row.Data.Tags, labelBy),
+	}
+
+	if row.Metric == "data_sent" {
+		parsed.BytesSent = uint64(row.Data.Value)
+	} else {
+		parsed.BytesReceived = uint64(row.Data.Value)
+	}
+
+	return parsed
+}
+
+func K6Label(tags K6MetricTags, labelBy string) string {
+	switch labelBy {
+	case "group":
+		return tags.Group
+	case "scenario":
+		return tags.Scenario
+	default:
+		return tags.Name
+	}
+}
+
+func TranslateK6CustomMetric(row K6Metric, metricType string, labelBy string) CustomMetricPoint {
+	label := K6Label(row.Data.Tags, labelBy)
+	if row.Metric == "checks" {
+		label = row.Data.Tags.Check
+	}
+
+	return CustomMetricPoint{
+		Metric:    row.Metric,
+		Type:      metricType,
+		Label:     label,
+		TimeStamp: ParseTimeStampMillis(row.Data.Time),
+		Value:     row.Data.Value,
+	}
+}
+
+func BuildColumnIndicesK6Csv(row []string) (map[string]int, error) {
+	// metric_name,timestamp,metric_value,check,error,error_code,expected_response,group,method,name,proto,scenario,service,status,subproto,tls_version,url,extra_tags,metadata
+	indices := map[string]int{
+		"metric_name":  -1,
+		"timestamp":    -1,
+		"metric_value": -1,
+	}
+
+	for i, column := range row {
+		indices[column] = i
+	}
+
+	missing := []string{}
+	for _, column := range []string{"metric_name", "timestamp", "metric_value"} {
+		if indices[column] == -1 {
+			missing = append(missing, column)
+		}
+	}
+
+	if len(missing) > 0 {
+		return nil, errors.New("missing column(s): " + strings.Join(missing, ", "))
+	}
+
+	return indices, nil
+}
+
+func TranslateK6CsvRow(row []string, indices map[string]int) K6Metric {
+	column := func(name string) string {
+		index, ok := indices[name]
+		if !ok || index >= len(row) {
+			return ""
+		}
+		return row[index]
+	}
+
+	value, err := strconv.ParseFloat(column("metric_value"), 64)
+	if err != nil {
+		log.Println("error parsing metric_value", err)
+	}
+
+	return K6Metric{
+		Type:   "Point",
+		Metric: column("metric_name"),
+		Data: K6MetricData{
+			Time:  column("timestamp"),
+			Value: value,
+			Tags: K6MetricTags{
+				Check:            column("check"),
+				Error:            column("error"),
+				ErrorCode:        column("error_code"),
+				ExpectedResponse: column("expected_response"),
+				Group:            column("group"),
+				Method:           column("method"),
+				Name:             column("name"),
+				Proto:            column("proto"),
+				Scenario:         column("scenario"),
+				Status:           column("status"),
+				URL:              column("url"),
+			},
+		},
 	}
 }
+
+type K6Summary struct {
+	Metrics map[string]K6SummaryMetric `json:"metrics"`
+	State   struct {
+		TestRunDurationMs float64 `json:"testRunDurationMs"`
+	} `json:"state"`
+}
+
+// K6SummaryMetric holds the values of a metric. --summary-export writes them
+// directly on the metric, handleSummary nests them under "values".
+type K6SummaryMetric map[string]interface{}
+
+func (m K6SummaryMetric) Value(key string) float64 {
+	value, _ := m.values()[key].(float64)
+	return value
+}
+
+func (m K6SummaryMetric) Has(key string) bool {
+	_, ok := m.values()[key].(float64)
+	return ok
+}
+
+func (m K6SummaryMetric) values() map[string]interface{} {
+	if nested, ok := m["values"].(map[string]interface{}); ok {
+		return nested
+	}
+	return map[string]interface{}(m)
+}
+
+// K6SummaryDuration is the duration of the run, from the state written since
+// k6 0.30, or from the request count and rate otherwise. It is zero when
+// neither is available.
+func K6SummaryDuration(summary K6Summary) time.Duration {
+	if summary.State.TestRunDurationMs > 0 {
+		return time.Duration(summary.State.TestRunDurationMs * float64(time.Millisecond))
+	}
+
+	requests := summary.Metrics["http_reqs"]
+	if rate := requests.Value("rate"); rate > 0 {
+		return time.Duration(requests.Value("count") / rate * float64(time.Second))
+	}
+	return 0
+}
+
+func TranslateK6Summary(summary K6Summary, labelBy string) *SummaryData {
+	result := &SummaryData{
+		Summary:        translateK6SummaryMetrics(summary.Metrics, ""),
+		SummaryByLabel: make(map[string]MetricSummary),
+	}
+
+	// labeled values are only available for submetrics with thresholds,
+	// eg. http_req_duration{name:/v1/orders}
+	tag := labelBy
+	if tag == "" {
+		tag = "name"
+	}
+	prefix := "http_req_duration{" + tag + ":"
+	for key := range summary.Metrics {
+		if strings.HasPrefix(key, prefix) && strings.HasSuffix(key, "}") {
+			label := key[len(prefix) : len(key)-1]
+			result.SummaryByLabel[label] = translateK6SummaryMetrics(summary.Metrics, "{"+tag+":"+label+"}")
+		}
+	}
+
+	return result
+}
+
+func translateK6SummaryMetrics(metrics map[string]K6SummaryMetric, submetric string) MetricSummary {
+	duration := metrics["http_req_duration"+submetric]
+	requests := metrics["http_reqs"+submetric]
+	failed := metrics["http_req_failed"+submetric]
+
+	summary := MetricSummary{
+		Latencies: &Latencies{
+			AvgMs: duration.Value("avg"),
+			MinMs: duration.Value("min"),
+			MaxMs: duration.Value("max"),
+			P50Ms: duration.Value("med"),
+			P75Ms: duration.Value("p(75)"),
+			P90Ms: duration.Value("p(90)"),
+			P95Ms: duration.Value("p(95)"),
+			P99Ms: duration.Value("p(99)"),
+		},
+		TotalRequests: uint64(requests.Value("count")),
+		// rate metrics count non-zero values as passes, ie. failed requests
+		TotalFailures:   uint64(failed.Value("passes")),
+		MaxVirtualUsers: uint64(metrics["vus_max"].Value("max")),
+	}
+
+	// the default summaryTrendStats don't include p(75) and p(99)
+	for key, percentile := range map[string]Percentiles{
+		"med":   Percentile50,
+		"p(75)": Percentile75,
+		"p(90)": Percentile90,
+		"p(95)": Percentile95,
+		"p(99)": Percentile99,
+	} {
+		if !duration.Has(key) {
+			summary.Latencies.Unreported |= percentile
+		}
+	}
+
+	if submetric != "" {
+		summary.Label = submetric[strings.Index(submetric, ":")+1 : len(submetric)-1]
+		summary.MaxVirtualUsers = 0
+	} else {
+		// the summary only has means, so peaks are left empty
+		received := metrics["data_received"]
+		sent := metrics["data_sent"]
+		summary.Throughput = &ThroughputSummary{
+			MeanRequestsPerSecond:      requests.Value("rate"),
+			MeanBytesReceivedPerSecond: received.Value("rate"),
+			MeanBytesSentPerSecond:     sent.Value("rate"),
+			TotalBytesReceived:         uint64(received.Value("count")),
+			TotalBytesSent:             uint64(sent.Value("count")),
+		}
+		if summary.TotalRequests > 0 {
+			summary.Throughput.AvgResponseBytes = received.Value("count") / float64(summary.TotalRequests)
+		}
+	}
+
+	roundLatencies(summary.Latencies)
+	return summary
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"strconv"
 	"strings"
+	"time"
 )
 
 func buildDefaultColumnIndicesLocust() map[string]int {
@@ -41,47 +42,127 @@ func BuildColumnIndicesLocust(row []string) (map[string]int, error) {
 		return nil, errors.New("missing column(s): " + strings.Join(missing, ", "))
 	}
 
+	// optional columns used for bandwidth and percentiles
+	for i, header := range row {
+		if _, ok := locustOptionalColumns[header]; ok {
+			indices[header] = i
+		}
+	}
+
 	return indices, nil
 }
 
-func TranslateLocustRow(row []string, indices map[string]int) UngroupedMetricDataPoint {
-	var (
-		requests     uint64
-		failures     uint64
-		virtualUsers uint64
-		latency      float64
-	)
+var locustOptionalColumns = map[string]bool{
+	"Total Average Content Size": true,
+	"Total Min Response Time":    true,
+	"50%":                        true,
+	"75%":                        true,
+	"90%":                        true,
+	"95%":                        true,
+	"99%":                        true,
+	"100%":                       true,
+}
+
+// locustStatsInterval is how often Locust writes stats history by default.
+const locustStatsInterval = time.Second
+
+// LocustTotals holds the running totals of a label, from the previous stats
+// history row.
+type LocustTotals struct {
+	TimeStamp    uint64
+	Requests     uint64
+	Failures     uint64
+	LatencySumMs float64
+	Bytes        float64
+}
+
+// TranslateLocustRow turns a stats history row into the requests made since
+// the previous row of the label. Locust only writes running totals, so counts
+// and the average latency are exact, while percentiles cover Locust's own
+// sliding window of recent requests and min is the running min.
+func TranslateLocustRow(row []string, indices map[string]int, previous LocustTotals) (AggregatedBucket, LocustTotals) {
+	var totals LocustTotals
 
 	requests, err := strconv.ParseUint(row[indices["Total Request Count"]], 10, 64)
 	if err != nil {
 		log.Fatalf("failed to parse requests: %v", err)
 	}
 
-	if requests > 1 {
-		log.Fatalf("requests > 1: %v", requests)
-	}
-
-	failures, err = strconv.ParseUint(row[indices["Total Failure Count"]], 10, 64)
+	failures, err := strconv.ParseUint(row[indices["Total Failure Count"]], 10, 64)
 	if err != nil {
 		log.Fatalf("failed to parse failures: %v", err)
 	}
 
-	virtualUsers, err = strconv.ParseUint(row[indices["User Count"]], 10, 64)
+	virtualUsers, err := strconv.ParseUint(row[indices["User Count"]], 10, 64)
 	if err != nil {
 		log.Fatalf("failed to parse virtual users: %v", err)
 	}
 
-	latency, err = strconv.ParseFloat(row[indices["Total Average Response Time"]], 64)
+	avgLatency, err := strconv.ParseFloat(row[indices["Total Average Response Time"]], 64)
 	if err != nil {
 		log.Fatalf("failed to parse latency: %v", err)
 	}
 
-	return UngroupedMetricDataPoint{
-		Requests:     requests,
-		Failures:     failures,
-		VirtualUsers: virtualUsers,
-		TimeStamp:    ParseTimeStampMillis(row[indices["Timestamp"]]) / 1000,
-		Latency:      uint64(latency),
+	totals.TimeStamp = ParseTimeStampMillis(row[indices["Timestamp"]])
+	totals.Requests = requests
+	totals.Failures = failures
+	totals.LatencySumMs = avgLatency * float64(requests)
+	totals.Bytes = locustFloat(row, indices, "Total Average Content Size") * float64(requests)
+
+	// rows are written at the end of the interval they cover
+	duration := locustStatsInterval
+	if previous.TimeStamp > 0 && totals.TimeStamp > previous.TimeStamp {
+		duration = time.Duration(totals.TimeStamp-previous.TimeStamp) * time.Millisecond
+	}
+
+	bucket := AggregatedBucket{
 		Label:        row[indices["Name"]],
+		TimeStamp:    totals.TimeStamp - uint64(duration.Milliseconds()),
+		Duration:     duration,
+		VirtualUsers: virtualUsers,
+		Latencies:    &Latencies{},
+	}
+
+	// totals restart when stats are reset
+	if requests < previous.Requests {
+		previous = LocustTotals{}
+	}
+
+	bucket.Requests = requests - previous.Requests
+	if failures >= previous.Failures {
+		bucket.Failures = failures - previous.Failures
+	}
+	if bucket.Requests > 0 {
+		bucket.Latencies = &Latencies{
+			AvgMs: (totals.LatencySumMs - previous.LatencySumMs) / float64(bucket.Requests),
+			MinMs: locustFloat(row, indices, "Total Min Response Time"),
+			MaxMs: locustFloat(row, indices, "100%"),
+			P50Ms: locustFloat(row, indices, "50%"),
+			P75Ms: locustFloat(row, indices, "75%"),
+			P90Ms: locustFloat(row, indices, "90%"),
+			P95Ms: locustFloat(row, indices, "95%"),
+			P99Ms: locustFloat(row, indices, "99%"),
+		}
+		roundLatencies(bucket.Latencies)
+	}
+	if totals.Bytes > previous.Bytes {
+		bucket.BytesReceived = uint64(totals.Bytes - previous.Bytes)
+	}
+
+	return bucket, totals
+}
+
+// locustFloat reads an optional column, Locust writes N/A before the first
+// request.
+func locustFloat(row []string, indices map[string]int, column string) float64 {
+	index, ok := indices[column]
+	if !ok {
+		return 0
+	}
+
+	value, err := strconv.ParseFloat(row[index], 64)
+	if err != nil {
+		return 0
 	}
+	return value
 }
@@ -0,0 +1,322 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nbomberScenarioStep is the step name NBomber uses for the stats of a whole
+// scenario.
+const nbomberScenarioStep = "global information"
+
+// NBomberStepStats are the stats of a step, or of a scenario when Step is
+// empty, since the start of the run. Latencies are of successful requests.
+type NBomberStepStats struct {
+	Scenario      string
+	Step          string
+	Duration      time.Duration
+	Ok            uint64
+	Failed        uint64
+	VirtualUsers  uint64
+	Latencies     Latencies
+	BytesReceived float64
+}
+
+// NBomberTotals holds the running totals of a step, from its previous stats.
+type NBomberTotals struct {
+	Duration     time.Duration
+	Ok           uint64
+	Failed       uint64
+	LatencySumMs float64
+	Bytes        float64
+}
+
+func NBomberLabel(stats NBomberStepStats) string {
+	if stats.Step == "" {
+		return stats.Scenario
+	}
+	return stats.Scenario + " / " + stats.Step
+}
+
+func buildDefaultColumnIndicesNBomber() map[string]int {
+	// test_suite,test_name,scenario,duration,step_name,request_count,ok,failed,rps,min,mean,max,50_percent,75_percent,95_percent,99_percent,std_dev,data_transfer_min_kb,data_transfer_mean_kb,data_transfer_max_kb,data_transfer_all_mb
+	// nbomber,checkout,checkout_flow,00:01:00,login,1200,1195,5,20,1.2,35.4,410.8,30.1,42.2,80.5,120.3,12.1,0.5,1.2,2.0,1.4
+	return map[string]int{
+		"scenario":  -1,
+		"duration":  -1,
+		"step_name": -1,
+		"ok":        -1,
+		"failed":    -1,
+		"mean":      -1,
+	}
+}
+
+var nbomberOptionalColumns = map[string]bool{
+	"min":                  true,
+	"max":                  true,
+	"50_percent":           true,
+	"75_percent":           true,
+	"95_percent":           true,
+	"99_percent":           true,
+	"data_transfer_all_mb": true,
+}
+
+func BuildColumnIndicesNBomber(row []string) (map[string]int, error) {
+	indices := buildDefaultColumnIndicesNBomber()
+	for i, header := range row {
+		header = strings.TrimSpace(header)
+		if _, ok := indices[header]; ok || nbomberOptionalColumns[header] {
+			indices[header] = i
+		}
+	}
+
+	missing := []string{}
+
+	for column, index := range indices {
+		if index == -1 {
+			missing = append(missing, column)
+		}
+	}
+
+	if len(missing) > 0 {
+		return nil, errors.New("missing column(s): " + strings.Join(missing, ", "))
+	}
+
+	return indices, nil
+}
+
+// ParseNBomberCsvRow reads a row of the CSV report.
+func ParseNBomberCsvRow(row []string, indices map[string]int) (NBomberStepStats, error) {
+	duration, err := ParseTimeSpan(row[indices["duration"]])
+	if err != nil {
+		return NBomberStepStats{}, err
+	}
+
+	ok, err := strconv.ParseUint(strings.TrimSpace(row[indices["ok"]]), 10, 64)
+	if err != nil {
+		return NBomberStepStats{}, fmt.Errorf("failed to parse ok: %v", err)
+	}
+
+	failed, err := strconv.ParseUint(strings.TrimSpace(row[indices["failed"]]), 10, 64)
+	if err != nil {
+		return NBomberStepStats{}, fmt.Errorf("failed to parse failed: %v", err)
+	}
+
+	column := func(name string) float64 {
+		index, ok := indices[name]
+		if !ok {
+			return 0
+		}
+		value, _ := strconv.ParseFloat(strings.TrimSpace(row[index]), 64)
+		return value
+	}
+
+	stats := NBomberStepStats{
+		Scenario: row[indices["scenario"]],
+		Step:     row[indices["step_name"]],
+		Duration: duration,
+		Ok:       ok,
+		Failed:   failed,
+		Latencies: Latencies{
+			AvgMs: column("mean"),
+			MinMs: column("min"),
+			MaxMs: column("max"),
+			P50Ms: column("50_percent"),
+			P75Ms: column("75_percent"),
+			P95Ms: column("95_percent"),
+			P99Ms: column("99_percent"),
+			// NBomber doesn't report p90
+			Unreported: Percentile90,
+		},
+		BytesReceived: column("data_transfer_all_mb") * 1024 * 1024,
+	}
+	if stats.Step == nbomberScenarioStep {
+		stats.Step = ""
+	}
+
+	for name, percentile := range map[string]Percentiles{
+		"50_percent": Percentile50,
+		"75_percent": Percentile75,
+		"95_percent": Percentile95,
+		"99_percent": Percentile99,
+	} {
+		if _, ok := indices[name]; !ok {
+			stats.Latencies.Unreported |= percentile
+		}
+	}
+
+	return stats, nil
+}
+
+// NBomberNodeStats is the JSON serialization of NBomber's NodeStats.
+type NBomberNodeStats struct {
+	TestInfo struct {
+		Created time.Time
+	}
+	ScenarioStats []struct {
+		ScenarioName        string
+		Duration            string
+		Ok                  *NBomberMeasurementStats
+		Fail                *NBomberMeasurementStats
+		LoadSimulationStats struct {
+			SimulationName string
+			Value          uint64
+		}
+		StepStats []struct {
+			StepName string
+			Ok       NBomberMeasurementStats
+			Fail     NBomberMeasurementStats
+		}
+	}
+}
+
+type NBomberMeasurementStats struct {
+	Request struct {
+		Count uint64
+	}
+	Latency struct {
+		MinMs     float64
+		MeanMs    float64
+		MaxMs     float64
+		Percent50 float64
+		Percent75 float64
+		Percent95 float64
+		Percent99 float64
+	}
+	DataTransfer struct {
+		AllBytes float64
+	}
+}
+
+// NBomberStepStatsFromNode flattens node stats into the stats of each step,
+// and of each scenario when NBomber reports them.
+func NBomberStepStatsFromNode(node NBomberNodeStats) ([]NBomberStepStats, error) {
+	var steps []NBomberStepStats
+	for _, scenario := range node.ScenarioStats {
+		duration, err := ParseTimeSpan(scenario.Duration)
+		if err != nil {
+			return nil, err
+		}
+
+		// keep_constant and ramping_constant simulations report copies,
+		// inject simulations report a rate
+		var virtualUsers uint64
+		if strings.HasSuffix(scenario.LoadSimulationStats.SimulationName, "constant") {
+			virtualUsers = scenario.LoadSimulationStats.Value
+		}
+
+		if scenario.Ok != nil && scenario.Fail != nil {
+			steps = append(steps, translateNBomberMeasurement(scenario.ScenarioName, "", duration, virtualUsers, *scenario.Ok, *scenario.Fail))
+		}
+		for _, step := range scenario.StepStats {
+			name := step.StepName
+			if name == nbomberScenarioStep {
+				name = ""
+			}
+			steps = append(steps, translateNBomberMeasurement(scenario.ScenarioName, name, duration, virtualUsers, step.Ok, step.Fail))
+		}
+	}
+	return steps, nil
+}
+
+func translateNBomberMeasurement(scenario string, step string, duration time.Duration, virtualUsers uint64, ok NBomberMeasurementStats, fail NBomberMeasurementStats) NBomberStepStats {
+	return NBomberStepStats{
+		Scenario:     scenario,
+		Step:         step,
+		Duration:     duration,
+		Ok:           ok.Request.Count,
+		Failed:       fail.Request.Count,
+		VirtualUsers: virtualUsers,
+		Latencies: Latencies{
+			AvgMs: ok.Latency.MeanMs,
+			MinMs: ok.Latency.MinMs,
+			MaxMs: ok.Latency.MaxMs,
+			P50Ms: ok.Latency.Percent50,
+			P75Ms: ok.Latency.Percent75,
+			P95Ms: ok.Latency.Percent95,
+			P99Ms: ok.Latency.Percent99,
+			// NBomber doesn't report p90
+			Unreported: Percentile90,
+		},
+		BytesReceived: ok.DataTransfer.AllBytes + fail.DataTransfer.AllBytes,
+	}
+}
+
+// TranslateNBomberStats turns stats into the requests made since the previous
+// stats of the step. NBomber reports totals since the start of the run, so
+// counts and the average latency are exact, while percentiles, min and max
+// cover the run so far.
+func TranslateNBomberStats(stats NBomberStepStats, startedAt uint64, previous NBomberTotals) (AggregatedBucket, NBomberTotals) {
+	totals := NBomberTotals{
+		Duration:     stats.Duration,
+		Ok:           stats.Ok,
+		Failed:       stats.Failed,
+		LatencySumMs: stats.Latencies.AvgMs * float64(stats.Ok),
+		Bytes:        stats.BytesReceived,
+	}
+
+	// stats restart when a scenario is run again
+	if stats.Ok < previous.Ok || stats.Failed < previous.Failed || stats.Duration < previous.Duration {
+		previous = NBomberTotals{}
+	}
+
+	bucket := AggregatedBucket{
+		Label:        NBomberLabel(stats),
+		TimeStamp:    startedAt + uint64(previous.Duration.Milliseconds()),
+		Duration:     stats.Duration - previous.Duration,
+		Requests:     stats.Ok + stats.Failed - previous.Ok - previous.Failed,
+		Failures:     stats.Failed - previous.Failed,
+		VirtualUsers: stats.VirtualUsers,
+		Latencies:    &Latencies{},
+	}
+
+	if ok := stats.Ok - previous.Ok; ok > 0 {
+		latencies := stats.Latencies
+		latencies.AvgMs = (totals.LatencySumMs - previous.LatencySumMs) / float64(ok)
+		roundLatencies(&latencies)
+		bucket.Latencies = &latencies
+	}
+	if totals.Bytes > previous.Bytes {
+		bucket.BytesReceived = uint64(totals.Bytes - previous.Bytes)
+	}
+
+	return bucket, totals
+}
+
+// ParseTimeSpan reads a .NET TimeSpan, eg. 00:01:30 or 1.02:00:00.5.
+func ParseTimeSpan(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+
+	var days time.Duration
+	if index := strings.Index(value, "."); index != -1 && index < strings.Index(value, ":") {
+		parsed, err := strconv.ParseUint(value[:index], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse duration %s: %v", value, err)
+		}
+		days = time.Duration(parsed) * 24 * time.Hour
+		value = value[index+1:]
+	}
+
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("failed to parse duration %s", value)
+	}
+
+	hours, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse duration %s: %v", value, err)
+	}
+	minutes, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse duration %s: %v", value, err)
+	}
+	seconds, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse duration %s: %v", value, err)
+	}
+
+	return days + time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds*float64(time.Second)), nil
+}
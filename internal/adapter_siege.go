@@ -0,0 +1,110 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+func buildDefaultColumnIndicesSiege() map[string]int {
+	//       Date & Time,  Trans,  Elap Time,  Data Trans,  Resp Time,  Trans Rate,  Throughput,  Concurrent,    OKAY,   Failed
+	// 2022-03-17 10:00:12,   1000,      10.02,           2,       0.05,       99.80,        0.20,        4.99,    1000,       0
+	return map[string]int{
+		"Date & Time": -1,
+		"Trans":       -1,
+		"Elap Time":   -1,
+		"Data Trans":  -1,
+		"Resp Time":   -1,
+		"Trans Rate":  -1,
+		"Throughput":  -1,
+		"Concurrent":  -1,
+		"OKAY":        -1,
+		"Failed":      -1,
+	}
+}
+
+// BuildColumnIndicesSiege reads the header of siege.log, which pads columns
+// with spaces.
+func BuildColumnIndicesSiege(row []string) (map[string]int, error) {
+	indices := buildDefaultColumnIndicesSiege()
+	for i, header := range row {
+		if _, ok := indices[strings.TrimSpace(header)]; ok {
+			indices[strings.TrimSpace(header)] = i
+		}
+	}
+
+	missing := []string{}
+
+	for column, index := range indices {
+		if index == -1 {
+			missing = append(missing, column)
+		}
+	}
+
+	if len(missing) > 0 {
+		return nil, errors.New("missing column(s): " + strings.Join(missing, ", "))
+	}
+
+	return indices, nil
+}
+
+// siegeMegabyte is the unit of siege's data transferred and throughput.
+const siegeMegabyte = 1024 * 1024
+
+// TranslateSiegeRow maps a siege.log line, the totals of one siege run, to a
+// summary. Time stamps are in milliseconds. The data is approximate:
+//   - only the average response time is logged, so percentiles are empty
+//   - virtual users are the average concurrency, not the peak
+//   - data transferred is logged in rounded megabytes
+func TranslateSiegeRow(row []string, indices map[string]int) (*SummaryData, error) {
+	values := make(map[string]float64)
+	for column, index := range indices {
+		if column == "Date & Time" {
+			continue
+		}
+
+		value, err := strconv.ParseFloat(strings.TrimSpace(row[index]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", column, err)
+		}
+		values[column] = value
+	}
+
+	stoppedAt, ok := tryParseTimeStampMillis(strings.TrimSpace(row[indices["Date & Time"]]))
+	if !ok {
+		return nil, fmt.Errorf("failed to parse date: %s", row[indices["Date & Time"]])
+	}
+
+	// transactions count responses, failed transactions got none, and
+	// responses with a 4xx or 5xx status aren't okay
+	transactions := uint64(values["Trans"])
+	failed := uint64(values["Failed"])
+	okay := uint64(values["OKAY"])
+	summary := MetricSummary{
+		// siege only logs the average response time
+		Latencies:       &Latencies{AvgMs: values["Resp Time"] * 1000, Unreported: AllPercentiles},
+		TotalRequests:   transactions + failed,
+		TotalFailures:   failed,
+		MaxVirtualUsers: uint64(math.Ceil(values["Concurrent"])),
+		Throughput: &ThroughputSummary{
+			MeanRequestsPerSecond:      values["Trans Rate"],
+			MeanBytesReceivedPerSecond: values["Throughput"] * siegeMegabyte,
+			TotalBytesReceived:         uint64(values["Data Trans"] * siegeMegabyte),
+		},
+	}
+	if okay < transactions {
+		summary.TotalFailures += transactions - okay
+	}
+	if transactions > 0 {
+		summary.Throughput.AvgResponseBytes = float64(summary.Throughput.TotalBytesReceived) / float64(transactions)
+	}
+	roundLatencies(summary.Latencies)
+
+	return &SummaryData{
+		Summary:   summary,
+		StartedAt: stoppedAt - uint64(values["Elap Time"]*1000),
+		StoppedAt: stoppedAt,
+	}, nil
+}
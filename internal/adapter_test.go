@@ -1,8 +1,17 @@
 package internal
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"testing"
+	"testing/iotest"
 	"time"
 )
 
@@ -118,6 +127,27 @@ func TestBuildColumnIndicesV2(t *testing.T) {
 	}
 }
 
+func TestTranslateJmeterRowSampleWithoutLatency(t *testing.T) {
+	headers := []string{}
+	row := []string{}
+	for i, header := range sampleHeaders {
+		if header != "Latency" {
+			headers = append(headers, header)
+			row = append(row, sampleRow[i])
+		}
+	}
+
+	indices, err := BuildColumnIndicesV2(headers)
+	if err != nil {
+		t.Fatal("Failed to build column indices without Latency: ", err)
+	}
+
+	sample := TranslateJmeterRowSample(row, indices)
+	if sample.Latency != 0 || sample.Connect != 100 {
+		t.Error("Failed to parse row without Latency: ", sample.Latency, " ", sample.Connect)
+	}
+}
+
 func TestTranslateJmeterRowSample(t *testing.T) {
 	indices, err := BuildColumnIndicesV2(sampleHeaders)
 	if err != nil {
@@ -191,14 +221,50 @@ func TestTranslateJmeterRowSample(t *testing.T) {
 	}
 }
 
+func TestTranslateJmeterRowTimings(t *testing.T) {
+	indices, err := BuildColumnIndices(sampleHeaders)
+	if err != nil {
+		t.Fatal("Failed to build column indices: ", err)
+	}
+
+	row := TranslateJmeterRow(sampleRow, indices)
+	if !row.HasTimings {
+		t.Error("Failed to parse timings")
+	}
+
+	if row.ServerLatency != 100 || row.ConnectTime != 100 || row.IdleTime != 100 {
+		t.Error("Failed to parse timings: ", row.ServerLatency, row.ConnectTime, row.IdleTime, " expected: ", 100)
+	}
+}
+
+func TestK6CollectorAddsTimingsToRequest(t *testing.T) {
+	collector := newK6Collector(ParseOptions{})
+	collector.add(sampleK6Row)
+
+	waiting := sampleK6Row
+	waiting.Metric = "http_req_waiting"
+	waiting.Data.Value = 3900
+	collector.add(waiting)
+
+	connecting := sampleK6Row
+	connecting.Metric = "http_req_connecting"
+	connecting.Data.Value = 12
+	collector.add(connecting)
+
+	row := collector.result().Rows[0]
+	if !row.HasTimings || row.ServerLatency != 3900 || row.ConnectTime != 12 {
+		t.Error("Failed to add timings: ", row.ServerLatency, row.ConnectTime, " expected: ", 3900, 12)
+	}
+}
+
 func TestTranslateK6Row(t *testing.T) {
 	row := TranslateK6Row(sampleK6Row)
 	if row.Requests != 1 {
 		t.Error("Failed to parse requests: ", row.Requests, " expected: ", 1)
 	}
 
-	if row.TimeStamp != 1647472912 {
-		t.Error("Failed to parse timestamp: ", row.TimeStamp, " expected: ", 1647472912)
+	if row.TimeStamp != 1647472912508 {
+		t.Error("Failed to parse timestamp: ", row.TimeStamp, " expected: ", 1647472912508)
 	}
 
 	if row.Latency != 4009 {
@@ -212,24 +278,866 @@ func TestTranslateLocustRow(t *testing.T) {
 		t.Error("Failed to build locust column indices: ", err)
 	}
 
-	row := TranslateLocustRow(sampleLocustRow, indices)
-	if row.Requests != 1 {
-		t.Error("Failed to parse requests: ", row.Requests, " expected: ", 1)
+	bucket, totals := TranslateLocustRow(sampleLocustRow, indices, LocustTotals{})
+	if bucket.Requests != 1 {
+		t.Error("Failed to parse requests: ", bucket.Requests, " expected: ", 1)
+	}
+
+	if bucket.TimeStamp != 1647453611000 || bucket.Duration != time.Second {
+		t.Error("Failed to parse timestamp: ", bucket.TimeStamp, " expected: ", 1647453611000)
+	}
+
+	if bucket.Latencies.AvgMs != 200 {
+		t.Error("Failed to parse latency: ", bucket.Latencies.AvgMs, " expected: ", 200)
+	}
+
+	if bucket.Label != "/v1/simulations/latency?level=low" {
+		t.Error("Failed to parse label: ", bucket.Label, " expected: ", "/v1/simulations/latency?level=low")
+	}
+
+	if bucket.VirtualUsers != 1 {
+		t.Error("Failed to parse virtual users: ", bucket.VirtualUsers, " expected: ", 1)
+	}
+
+	// running totals of 3 requests averaging 300ms, 2 seconds later
+	next := []string{"1647453614", "2", "GET", "/v1/simulations/latency?level=low", "3", "1", "300.00"}
+	bucket, _ = TranslateLocustRow(next, indices, totals)
+	if bucket.Requests != 2 || bucket.Failures != 1 || bucket.Latencies.AvgMs != 350 {
+		t.Error("Failed to parse interval: ", bucket.Requests, bucket.Failures, bucket.Latencies.AvgMs, " expected: ", 2, 1, 350)
+	}
+
+	if bucket.TimeStamp != 1647453612000 || bucket.Duration != 2*time.Second {
+		t.Error("Failed to parse interval: ", bucket.TimeStamp, bucket.Duration)
+	}
+}
+
+func TestParseDataFileLocustKeepsTotalsPerName(t *testing.T) {
+	// running totals are kept per type and name, so the Aggregated row of a
+	// time stamp isn't mistaken for the first row of a new series
+	contents := strings.Join([]string{
+		strings.Join(sampleLocustHeaders, ","),
+		"1647453612,1,GET,/cart,10,0,200.00",
+		"1647453612,1,,Aggregated,10,0,200.00",
+		"1647453614,1,GET,/cart,30,0,200.00",
+		"1647453614,1,,Aggregated,30,0,200.00",
+	}, "\n")
+	file := filepath.Join(t.TempDir(), "locust_stats_history.csv")
+	if err := os.WriteFile(file, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ParseDataFileLocust(file)
+	if err != nil {
+		t.Fatal("Failed to parse locust file: ", err)
+	}
+
+	var overall uint64
+	for _, bucket := range data.Buckets {
+		if bucket.Label == "" {
+			overall += bucket.Requests
+		}
+	}
+	if overall != 30 {
+		t.Error("Failed to count Aggregated rows once: ", overall, " expected: ", 30)
+	}
+}
+
+func TestTranslateK6CustomMetric(t *testing.T) {
+	metric := sampleK6Row
+	metric.Metric = "http_req_waiting"
+	metric.Data.Tags.Scenario = "checkout"
+
+	point := TranslateK6CustomMetric(metric, CustomMetricTrend, "scenario")
+	if point.Label != "checkout" {
+		t.Error("Failed to parse label: ", point.Label, " expected: ", "checkout")
+	}
+
+	if point.Type != CustomMetricTrend {
+		t.Error("Failed to parse type: ", point.Type, " expected: ", CustomMetricTrend)
+	}
+
+	if point.TimeStamp != 1647472912508 {
+		t.Error("Failed to parse timestamp: ", point.TimeStamp, " expected: ", 1647472912508)
+	}
+}
+
+func TestTranslateK6CsvRow(t *testing.T) {
+	header := []string{"metric_name", "timestamp", "metric_value", "check", "error", "error_code", "expected_response", "group", "method", "name", "proto", "scenario", "service", "status", "subproto", "tls_version", "url", "extra_tags", "metadata"}
+	row := []string{"http_req_duration", "1647472912", "4009.147", "", "", "", "true", "", "GET", "http://localhost:6700/", "HTTP/1.1", "default", "", "200", "", "", "http://localhost:6700/", "", ""}
+
+	indices, err := BuildColumnIndicesK6Csv(header)
+	if err != nil {
+		t.Error("Failed to build k6 csv column indices: ", err)
+	}
+
+	metric := TranslateK6CsvRow(row, indices)
+	if metric.Metric != "http_req_duration" {
+		t.Error("Failed to parse metric: ", metric.Metric, " expected: ", "http_req_duration")
+	}
+
+	parsed := TranslateK6Row(metric)
+	if parsed.TimeStamp != 1647472912000 {
+		t.Error("Failed to parse timestamp: ", parsed.TimeStamp, " expected: ", 1647472912000)
+	}
+
+	if parsed.Latency != 4009 {
+		t.Error("Failed to parse latency: ", parsed.Latency, " expected: ", 4009)
+	}
+
+	if parsed.Failures != 0 {
+		t.Error("Failed to parse failures: ", parsed.Failures, " expected: ", 0)
+	}
+}
+
+func TestTranslateK6Summary(t *testing.T) {
+	var summary K6Summary
+	err := json.Unmarshal([]byte(`{"metrics": {
+		"http_req_duration": {"avg": 120.5, "min": 10, "med": 100, "max": 900, "p(90)": 300, "p(95)": 450},
+		"http_req_duration{name:checkout}": {"avg": 200, "min": 20, "med": 180, "max": 900, "p(90)": 400, "p(95)": 500},
+		"http_reqs": {"count": 1000, "rate": 33.333333},
+		"http_req_failed": {"passes": 12, "fails": 988, "value": 0.012},
+		"vus_max": {"value": 50, "min": 50, "max": 50}
+	}}`), &summary)
+	if err != nil {
+		t.Error("Failed to parse k6 summary: ", err)
+	}
+
+	result := TranslateK6Summary(summary, "")
+	if result.Summary.TotalRequests != 1000 {
+		t.Error("Failed to parse requests: ", result.Summary.TotalRequests, " expected: ", 1000)
+	}
+
+	if result.Summary.TotalFailures != 12 {
+		t.Error("Failed to parse failures: ", result.Summary.TotalFailures, " expected: ", 12)
+	}
+
+	if result.Summary.MaxVirtualUsers != 50 {
+		t.Error("Failed to parse virtual users: ", result.Summary.MaxVirtualUsers, " expected: ", 50)
+	}
+
+	if result.Summary.Latencies.P50Ms != 100 {
+		t.Error("Failed to parse p50: ", result.Summary.Latencies.P50Ms, " expected: ", 100)
+	}
+
+	if result.SummaryByLabel["checkout"].Latencies.P95Ms != 500 {
+		t.Error("Failed to parse labeled p95: ", result.SummaryByLabel["checkout"].Latencies.P95Ms, " expected: ", 500)
+	}
+
+	// default summaryTrendStats have no p(75) or p(99)
+	if unreported := result.Summary.Latencies.Unreported; unreported != Percentile75|Percentile99 {
+		t.Error("Failed to leave out unreported percentiles: ", unreported)
+	}
+
+	if duration := K6SummaryDuration(summary); duration.Round(time.Second) != 30*time.Second {
+		t.Error("Failed to compute duration from the request rate: ", duration)
+	}
+}
+
+func TestTranslateGatlingRow(t *testing.T) {
+	layouts := [][]string{
+		// 3.4+
+		{"REQUEST", "", "GET low latency", "1647457744634", "1647457744825", "KO", "status.find.is(200), but actually found 500"},
+		// 3.0 - 3.3
+		{"REQUEST", "1", "", "GET low latency", "1647457744634", "1647457744825", "KO", "status.find.is(200), but actually found 500"},
+	}
+
+	for _, layout := range layouts {
+		row, ok := TranslateGatlingRow(layout)
+		if !ok {
+			t.Error("Failed to parse gatling row: ", layout)
+			continue
+		}
+
+		if row.Label != "GET low latency" {
+			t.Error("Failed to parse label: ", row.Label, " expected: ", "GET low latency")
+		}
+
+		if row.Latency != 191 {
+			t.Error("Failed to parse latency: ", row.Latency, " expected: ", 191)
+		}
+
+		if row.Failures != 1 || row.FailureMessage != "status.find.is(200), but actually found 500" {
+			t.Error("Failed to parse failure: ", row.Failures, " ", row.FailureMessage)
+		}
+	}
+
+	if _, ok := TranslateGatlingRow([]string{"REQUEST", "", "GET"}); ok {
+		t.Error("Expected short gatling row to be skipped")
+	}
+
+	group, ok := TranslateGatlingGroupRow([]string{"GROUP", "checkout,payment", "1647457744634", "1647457745825", "380", "OK"})
+	if !ok {
+		t.Fatal("Failed to parse gatling group row")
+	}
+
+	if group.Label != "checkout / payment" || group.Latency != 380 || !group.Transaction {
+		t.Error("Failed to parse gatling group row: ", group.Label, " ", group.Latency, " ", group.Transaction)
+	}
+}
+
+func TestParseJmeterXmlTransactions(t *testing.T) {
+	results := `<?xml version="1.0" encoding="UTF-8"?>
+<testResults version="1.2">
+<sample t="300" lt="0" ts="1647453612000" s="false" lb="checkout" rc="200" rm="" tn="Thread Group 1-1" dt="" na="2">
+  <httpSample t="100" lt="80" ct="10" ts="1647453612000" s="true" lb="GET /cart" rc="200" tn="Thread Group 1-1" dt="text" by="512" na="2"/>
+  <httpSample t="200" lt="150" ct="0" ts="1647453612100" s="false" lb="POST /orders" rc="500" tn="Thread Group 1-1" dt="text" na="2">
+    <assertionResult>
+      <name>Response Assertion</name>
+      <failure>true</failure>
+      <error>false</error>
+      <failureMessage>Test failed</failureMessage>
+    </assertionResult>
+  </httpSample>
+</sample>
+<httpSample t="50" ts="1647453613000" s="true" lb="GET /health" rc="200" rm="Number of samples in transaction : 1, number of failing samples : 0" dt="" na="2"/>
+</testResults>`
+
+	parsed := &ParsedData{}
+	if err := parseJmeterXml(strings.NewReader(results), parsed); err != nil {
+		t.Fatal("Failed to parse XML results: ", err)
+	}
+	rows := parsed.Rows
+
+	if len(rows) != 4 {
+		t.Fatal("Failed to parse samples: ", len(rows), " expected: ", 4)
+	}
+
+	expected := []bool{true, false, false, true}
+	for i, row := range rows {
+		if row.Transaction != expected[i] {
+			t.Error("Failed to recognize transaction: ", row.Label, " expected: ", expected[i])
+		}
+	}
+
+	if rows[1].BytesReceived != 512 || rows[1].ConnectTime != 10 || rows[1].ServerLatency != 80 {
+		t.Error("Failed to parse sample attributes: ", rows[1])
+	}
+
+	if rows[2].AssertionName != "Response Assertion" || rows[2].FailureMessage != "Test failed" {
+		t.Error("Failed to parse assertion: ", rows[2].AssertionName, rows[2].FailureMessage)
+	}
+}
+
+func TestParseJmeterXmlRedirects(t *testing.T) {
+	results := `<?xml version="1.0" encoding="UTF-8"?>
+<testResults version="1.2">
+<httpSample t="120" lt="40" ts="1647453612000" s="true" lb="GET /login" rc="200" rm="OK" tn="Thread Group 1-1" dt="text" na="1">
+  <httpSample t="40" lt="40" ts="1647453612000" s="true" lb="GET /login-0" rc="302" rm="Found" tn="Thread Group 1-1" dt="" na="1"/>
+  <httpSample t="80" lt="70" ts="1647453612040" s="true" lb="GET /login-1" rc="200" rm="OK" tn="Thread Group 1-1" dt="text" na="1"/>
+</httpSample>
+</testResults>`
+
+	parsed := &ParsedData{}
+	if err := parseJmeterXml(strings.NewReader(results), parsed); err != nil {
+		t.Fatal("Failed to parse XML results: ", err)
+	}
+	rows := parsed.Rows
+
+	if len(rows) != 3 {
+		t.Fatal("Failed to parse samples: ", len(rows), " expected: ", 3)
+	}
+
+	for _, row := range rows {
+		if row.Transaction {
+			t.Error("Failed to keep redirected sample as a request: ", row.Label)
+		}
+	}
+}
+
+type countingReader struct {
+	reader io.Reader
+	read   int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += n
+	return n, err
+}
+
+// streamingSink records how much of the input was read when each row is added.
+type streamingSink struct {
+	*ParsedData
+	reader *countingReader
+	readAt []int
+}
+
+func (s *streamingSink) AddRow(row UngroupedMetricDataPoint) {
+	s.readAt = append(s.readAt, s.reader.read)
+	s.ParsedData.AddRow(row)
+}
+
+func TestParseJmeterXmlStreams(t *testing.T) {
+	results := `<?xml version="1.0" encoding="UTF-8"?>
+<testResults version="1.2">
+<sample t="300" ts="1647453612000" s="true" lb="Checkout" rc="200" dt="" na="2">
+  <httpSample t="100" ts="1647453612000" s="true" lb="GET /cart" rc="200" dt="text" na="2"/>
+</sample>
+<httpSample t="50" ts="1647453613000" s="true" lb="GET /health" rc="200" dt="text" na="2"/>
+</testResults>`
+
+	reader := &countingReader{reader: iotest.OneByteReader(strings.NewReader(results))}
+	sink := &streamingSink{ParsedData: &ParsedData{}, reader: reader}
+	if err := parseJmeterXml(reader, sink); err != nil {
+		t.Fatal("Failed to parse XML results: ", err)
+	}
+
+	if len(sink.Rows) != 3 || !sink.Rows[0].Transaction {
+		t.Fatal("Failed to parse samples: ", sink.Rows)
+	}
+
+	// the transaction and its request are added once the transaction closes
+	if end := strings.Index(results, "</sample>") + len("</sample>"); sink.readAt[0] > end+1 || sink.readAt[1] != sink.readAt[0] {
+		t.Error("Failed to add rows while reading: ", sink.readAt, " expected at: ", end)
+	}
+}
+
+func TestIsJmeterTransaction(t *testing.T) {
+	if !IsJmeterTransaction("Number of samples in transaction : 2, number of failing samples : 0", "text", "") {
+		t.Error("Failed to recognize transaction from response message")
+	}
+
+	if !IsJmeterTransaction("", "", "null") {
+		t.Error("Failed to recognize parent sample")
+	}
+
+	if IsJmeterTransaction("OK", "text", "http://localhost:8080") {
+		t.Error("Failed to recognize request")
+	}
+}
+
+func TestReadVegetaResults(t *testing.T) {
+	expected := VegetaResult{
+		Attack:    "checkout",
+		Seq:       3,
+		Code:      500,
+		Timestamp: time.Unix(0, 1647453612123456789),
+		Latency:   25 * time.Millisecond,
+		BytesOut:  20,
+		BytesIn:   512,
+		Error:     "500 Internal Server Error",
+		Method:    "POST",
+		URL:       "https://example.com/orders",
+	}
+
+	var encoded bytes.Buffer
+	if err := gob.NewEncoder(&encoded).Encode(expected); err != nil {
+		t.Fatal(err)
+	}
+
+	encodings := map[string]string{
+		"json": `{"attack":"checkout","seq":3,"code":500,"timestamp":"2022-03-16T18:00:12.123456789Z","latency":25000000,"bytes_out":20,"bytes_in":512,"error":"500 Internal Server Error","body":"","method":"POST","url":"https://example.com/orders","headers":{}}` + "\n",
+		"csv":  "1647453612123456789,500,25000000,20,512,500 Internal Server Error,,checkout,3,POST,https://example.com/orders,\n",
+		"gob":  encoded.String(),
+	}
+
+	for encoding, contents := range encodings {
+		results, err := readVegetaResults(bufio.NewReader(strings.NewReader(contents)))
+		if err != nil {
+			t.Fatal("Failed to read ", encoding, ": ", err)
+		}
+
+		if len(results) != 1 || !results[0].Timestamp.Equal(expected.Timestamp) {
+			t.Fatal("Failed to read ", encoding, ": ", results)
+		}
+
+		result := results[0]
+		result.Timestamp = expected.Timestamp
+		if result != expected {
+			t.Error("Failed to read ", encoding, ": ", result, " expected: ", expected)
+		}
+	}
+}
+
+func TestTranslateVegetaRow(t *testing.T) {
+	result := VegetaResult{
+		Attack:    "checkout",
+		Code:      302,
+		Timestamp: time.UnixMilli(1647453612123),
+		Latency:   25*time.Millisecond + 500*time.Microsecond,
+		BytesOut:  20,
+		BytesIn:   512,
+		Method:    "GET",
+		URL:       "https://example.com/cart",
 	}
 
-	if row.TimeStamp != 1647453612 {
-		t.Error("Failed to parse timestamp: ", row.TimeStamp, " expected: ", 1647453612)
+	row := TranslateVegetaRow(result, "")
+	if row.TimeStamp != 1647453612123 || row.Latency != 25 || row.Failures != 0 || row.ResponseCode != "302" {
+		t.Error("Failed to translate result: ", row)
 	}
 
-	if row.Latency != 200 {
-		t.Error("Failed to parse latency: ", row.Latency, " expected: ", 200)
+	if row.Label != "GET https://example.com/cart" || row.BytesReceived != 512 || row.BytesSent != 20 {
+		t.Error("Failed to translate result: ", row)
 	}
 
-	if row.Label != "/v1/simulations/latency?level=low" {
-		t.Error("Failed to parse label: ", row.Label, " expected: ", "/v1/simulations/latency?level=low")
+	if label := VegetaLabel(result, VegetaLabelByURL); label != "https://example.com/cart" {
+		t.Error("Failed to label by url: ", label)
 	}
 
-	if row.VirtualUsers != 1 {
-		t.Error("Failed to parse virtual users: ", row.VirtualUsers, " expected: ", 1)
+	result.Code = 0
+	result.Error = "dial tcp: connection refused"
+	if row := TranslateVegetaRow(result, ""); row.Failures != 1 || row.FailureMessage != result.Error {
+		t.Error("Failed to translate failed result: ", row)
+	}
+}
+
+func TestTranslateArtilleryReport(t *testing.T) {
+	period := func(start string, ok float64, p95 float64) string {
+		return `{
+			"period": "` + start + `",
+			"counters": {
+				"http.codes.200": ` + strconv.FormatFloat(ok, 'f', -1, 64) + `,
+				"http.codes.500": 1,
+				"errors.ETIMEDOUT": 1,
+				"http.downloaded_bytes": 1000,
+				"plugins.metrics-by-endpoint./cart.codes.200": ` + strconv.FormatFloat(ok, 'f', -1, 64) + `
+			},
+			"summaries": {
+				"http.response_time": {"min": 5, "max": 200, "count": 10, "mean": 40.123, "p50": 30, "p75": 50, "p90": 80, "p95": ` + strconv.FormatFloat(p95, 'f', -1, 64) + `, "p99": 150},
+				"plugins.metrics-by-endpoint.response_time./cart": {"min": 5, "max": 100, "count": 8, "mean": 20, "median": 18, "p75": 25, "p90": 40, "p95": 60, "p99": 90},
+				"vusers.session_length": {"count": 4, "mean": 5000}
+			}
+		}`
+	}
+
+	contents := `{
+		"aggregate": {
+			"counters": {"http.codes.200": 18, "http.codes.500": 2, "errors.ETIMEDOUT": 2, "http.downloaded_bytes": 2000, "plugins.metrics-by-endpoint./cart.codes.200": 18},
+			"summaries": {
+				"http.response_time": {"min": 5, "max": 200, "count": 20, "mean": 40, "p50": 30, "p75": 50, "p90": 80, "p95": 110, "p99": 150},
+				"plugins.metrics-by-endpoint.response_time./cart": {"min": 5, "max": 100, "count": 18, "mean": 20, "p50": 18, "p75": 25, "p90": 40, "p95": 60, "p99": 90}
+			},
+			"lastMetricAt": 1647453630000
+		},
+		"intermediate": [` + period("1647453620000", 9, 120) + `,` + period("1647453610000", 9, 100) + `]
+	}`
+
+	var report ArtilleryReport
+	if err := json.Unmarshal([]byte(contents), &report); err != nil {
+		t.Fatal(err)
+	}
+	parsed := TranslateArtilleryReport(report)
+
+	if len(parsed.Buckets) != 4 {
+		t.Fatal("Failed to translate periods: ", len(parsed.Buckets), " expected: ", 4)
+	}
+
+	overall := parsed.Buckets[0]
+	if overall.TimeStamp != 1647453610000 || overall.Duration != 10*time.Second || overall.Requests != 11 || overall.Failures != 2 {
+		t.Error("Failed to translate period: ", overall)
+	}
+
+	if overall.Latencies.P95Ms != 100 || overall.Latencies.AvgMs != 40.12 || overall.VirtualUsers != 2 {
+		t.Error("Failed to keep reported percentiles: ", overall.Latencies, overall.VirtualUsers)
+	}
+
+	if labeled := parsed.Buckets[1]; labeled.Label != "/cart" || labeled.Requests != 9 || labeled.Latencies.P50Ms != 18 {
+		t.Error("Failed to translate endpoint: ", labeled)
+	}
+
+	summary := parsed.Summary.Summary
+	if summary.TotalRequests != 22 || summary.TotalFailures != 4 || summary.Latencies.P95Ms != 110 || summary.Throughput.PeakRequestsPerSecond != 1.1 {
+		t.Error("Failed to translate aggregate: ", summary, summary.Throughput)
+	}
+
+	if parsed.Summary.StartedAt != 1647453610000 || parsed.Summary.StoppedAt != 1647453630000 || parsed.Summary.SummaryByLabel["/cart"].TotalRequests != 18 {
+		t.Error("Failed to translate run: ", parsed.Summary)
+	}
+}
+
+func TestArtilleryPeriodDuration(t *testing.T) {
+	periods := func(starts ...string) []ArtilleryPeriod {
+		var result []ArtilleryPeriod
+		for _, start := range starts {
+			result = append(result, ArtilleryPeriod{Period: json.Number(start)})
+		}
+		return result
+	}
+
+	// the period at 1647453620000 had no requests
+	if duration := artilleryPeriodDuration(periods("1647453610000", "1647453630000", "1647453640000")); duration != 10*time.Second {
+		t.Error("Failed to use the smallest gap: ", duration, " expected: ", 10*time.Second)
+	}
+
+	if duration := artilleryPeriodDuration(periods("1647453610000", "1647453614000", "1647453620000")); duration != artilleryDefaultPeriod {
+		t.Error("Failed to fall back to the default period: ", duration, " expected: ", artilleryDefaultPeriod)
+	}
+}
+
+func TestParseWrkOutput(t *testing.T) {
+	output := `Running 30s test @ http://127.0.0.1:8080/index.html
+  2 threads and 100 connections
+  Thread calibration: mean lat.: 1.234ms, rate sampling interval: 10ms
+  Thread Stats   Avg      Stdev     Max   +/- Stdev
+    Latency     1.11ms  525.17us   4.86ms   66.63%
+    Req/Sec     1.05k    95.07     1.55k    71.43%
+  Latency Distribution (HdrHistogram - Recorded Latency)
+ 50.000%    1.06ms
+ 75.000%    1.43ms
+ 90.000%    1.77ms
+ 99.000%    2.44ms
+ 99.900%    3.51ms
+100.000%    4.86ms
+
+  Detailed Percentile spectrum:
+       Value   Percentile   TotalCount 1/(1-Percentile)
+
+       0.066     0.000000            1         1.00
+       1.060     0.500000        29950         2.00
+       1.770     0.900000        53910        10.00
+       2.010     0.950000        56905        20.00
+       4.860     1.000000        59900          inf
+#[Mean    =        1.108, StdDeviation   =        0.525]
+#[Max     =        4.860, Total count    =        59900]
+#[Buckets =           27, SubBuckets     =         2048]
+----------------------------------------------------------
+  60004 requests in 30.00s, 21.46MB read
+  Socket errors: connect 0, read 2, write 0, timeout 1
+  Non-2xx or 3xx responses: 12
+Requests/sec:   2000.05
+Transfer/sec:    732.54KB
+`
+
+	result, err := ParseWrkOutput(strings.NewReader(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summary := TranslateWrkResult(result)
+	if result.URL != "http://127.0.0.1:8080/index.html" || result.Duration != 30*time.Second || summary.MaxVirtualUsers != 100 {
+		t.Error("Failed to parse run: ", result.URL, result.Duration, summary.MaxVirtualUsers)
+	}
+
+	if summary.TotalRequests != 60004 || summary.TotalFailures != 15 {
+		t.Error("Failed to parse requests: ", summary.TotalRequests, summary.TotalFailures, " expected: ", 60004, 15)
+	}
+
+	expected := Latencies{AvgMs: 1.11, MinMs: 0.07, MaxMs: 4.86, P50Ms: 1.06, P75Ms: 1.43, P90Ms: 1.77, P95Ms: 2.01, P99Ms: 2.44}
+	if *summary.Latencies != expected {
+		t.Error("Failed to parse latencies: ", *summary.Latencies, " expected: ", expected)
+	}
+
+	if summary.Throughput.MeanRequestsPerSecond != 2000.05 || summary.Throughput.TotalBytesReceived != 22502440 {
+		t.Error("Failed to parse throughput: ", summary.Throughput)
+	}
+
+	// plain wrk has no percentile spectrum, so p95 isn't reported
+	plain := output[:strings.Index(output, "  Detailed Percentile spectrum:")] + output[strings.Index(output, "----------"):]
+	result, err = ParseWrkOutput(strings.NewReader(plain))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summary = TranslateWrkResult(result)
+	if summary.Latencies.Unreported != Percentile95 || summary.Latencies.P95Ms != 0 {
+		t.Error("Failed to leave out p95: ", summary.Latencies)
+	}
+}
+
+func TestParseDataFileHeyStartedAt(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "hey.csv")
+	contents := "response-time,DNS+dialup,DNS,Request-write,Response-delay,Response-read,status-code,offset\n" +
+		"0.0218,0.0120,0.0011,0.0000,0.0094,0.0003,200,1.5010\n"
+	if err := os.WriteFile(file, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := ParseDataFileHey(file, ParseOptions{StartedAt: 1647453612000, DefaultLabel: "checkout"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if parsed.Metadata.StartedAt != 1647453612000 || parsed.Rows[0].TimeStamp != 1647453613501 {
+		t.Error("Failed to use the explicit start time: ", parsed.Metadata.StartedAt, parsed.Rows[0].TimeStamp)
+	}
+}
+
+func TestTranslateHeyRow(t *testing.T) {
+	indices, err := BuildColumnIndicesHey(strings.Split("response-time,DNS+dialup,DNS,Request-write,Response-delay,Response-read,status-code,offset", ","))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request, err := ParseHeyRow(strings.Split("0.0218,0.0120,0.0011,0.0000,0.0094,0.0003,503,1.5010", ","), indices)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	row := TranslateHeyRow(request, 1647453612000, "checkout")
+	if row.TimeStamp != 1647453613501 || row.Latency != 22 || row.Label != "checkout" || row.Failures != 1 {
+		t.Error("Failed to translate request: ", row)
+	}
+
+	if row.ConnectTime != 12 || row.ServerLatency != 9 || !row.HasTimings {
+		t.Error("Failed to translate timings: ", row.ConnectTime, row.ServerLatency)
+	}
+}
+
+func TestTranslateAbRow(t *testing.T) {
+	indices, err := BuildColumnIndicesAb(strings.Split("starttime\tseconds\tctime\tdtime\tttime\twait", "\t"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request, err := ParseAbRow(strings.Split("Thu Mar 17 10:00:12 2022\t1647511212\t3\t12\t15\t10", "\t"), indices)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	row := TranslateAbRow(request, "checkout")
+	if row.TimeStamp != 1647511212000 || row.Latency != 15 || row.ConnectTime != 3 || row.ServerLatency != 10 || row.Label != "checkout" {
+		t.Error("Failed to translate request: ", row)
+	}
+}
+
+func TestTranslateSiegeRow(t *testing.T) {
+	header := strings.Split("      Date & Time,  Trans,  Elap Time,  Data Trans,  Resp Time,  Trans Rate,  Throughput,  Concurrent,    OKAY,   Failed", ",")
+	indices, err := BuildColumnIndicesSiege(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	row := strings.Split("2022-03-17 10:00:12,   1000,      10.00,           2,       0.05,      100.00,        0.20,        4.99,     990,       5", ",")
+	summaryData, err := TranslateSiegeRow(row, indices)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summary := summaryData.Summary
+	if summary.TotalRequests != 1005 || summary.TotalFailures != 15 || summary.MaxVirtualUsers != 5 || summary.Latencies.AvgMs != 50 {
+		t.Error("Failed to translate run: ", summary.TotalRequests, summary.TotalFailures, summary.MaxVirtualUsers, summary.Latencies.AvgMs)
+	}
+
+	if summaryData.StoppedAt-summaryData.StartedAt != 10000 || summary.Throughput.TotalBytesReceived != 2*1024*1024 {
+		t.Error("Failed to translate totals: ", summaryData.StartedAt, summaryData.StoppedAt, summary.Throughput)
+	}
+
+	p50, p75, p90, p95, p99 := mapPercentiles(summary.Latencies)
+	for _, percentile := range []*float64{p50, p75, p90, p95, p99} {
+		if percentile != nil {
+			t.Error("Failed to leave out percentiles siege doesn't log: ", *percentile)
+		}
+	}
+}
+
+func TestTranslateGhzReport(t *testing.T) {
+	contents := `{
+  "date": "2022-03-17T10:00:12Z",
+  "endReason": "normal",
+  "options": {"call": "helloworld.Greeter.SayHello", "concurrency": 10},
+  "count": 200,
+  "total": 2000000000,
+  "average": 12500000,
+  "fastest": 2000000,
+  "slowest": 90000000,
+  "rps": 100,
+  "errorDistribution": {"rpc error: code = Unavailable desc = connection refused": 3},
+  "statusCodeDistribution": {"OK": 197, "Unavailable": 3},
+  "latencyDistribution": [{"percentage": 50, "latency": 11000000}, {"percentage": 99, "latency": 80250000}],
+  "details": [
+    {"timestamp": "2022-03-17T10:00:12.250Z", "latency": 12400000, "error": "", "status": "OK"},
+    {"timestamp": "2022-03-17T10:00:12.100Z", "latency": 3000000, "error": "rpc error: code = Unavailable desc = connection refused", "status": "Unavailable"}
+  ]
+}`
+
+	var report GhzReport
+	if err := json.Unmarshal([]byte(contents), &report); err != nil {
+		t.Fatal(err)
+	}
+
+	label := GhzLabel(report.Options)
+	if label != "helloworld.Greeter/SayHello" {
+		t.Error("Failed to label call: ", label)
+	}
+
+	row := TranslateGhzDetail(report.Details[1], label, report.Options.Concurrency)
+	if row.TimeStamp != 1647511212100 || row.Latency != 3 || row.Failures != 1 || row.ResponseCode != "Unavailable" || row.VirtualUsers != 10 {
+		t.Error("Failed to translate call: ", row)
+	}
+
+	summary := TranslateGhzSummary(report)
+	if summary.TotalRequests != 200 || summary.TotalFailures != 3 || summary.Latencies.P50Ms != 11 || summary.Latencies.P99Ms != 80.25 {
+		t.Error("Failed to translate totals: ", summary.TotalRequests, summary.TotalFailures, summary.Latencies)
+	}
+	if summary.Latencies.Unreported != Percentile75|Percentile90|Percentile95 {
+		t.Error("Failed to leave out percentiles missing from the distribution: ", summary.Latencies.Unreported)
+	}
+
+	report.LatencyDistribution = nil
+	if summary := TranslateGhzSummary(report); summary.Latencies.Unreported != AllPercentiles {
+		t.Error("Failed to leave out percentiles without a distribution: ", summary.Latencies.Unreported)
+	}
+}
+
+func TestTranslateFortioSummary(t *testing.T) {
+	contents := `{
+  "RunType": "HTTP",
+  "StartTime": "2022-03-17T10:00:12Z",
+  "ActualQPS": 99.5,
+  "ActualDuration": 10000000000,
+  "NumThreads": 8,
+  "DurationHistogram": {
+    "Count": 1000,
+    "Min": 0.002,
+    "Max": 0.35,
+    "Avg": 0.0125,
+    "Data": [
+      {"Start": 0.002, "End": 0.01, "Count": 600},
+      {"Start": 0.01, "End": 0.05, "Count": 380},
+      {"Start": 0.05, "End": 0.35, "Count": 20}
+    ],
+    "Percentiles": [{"Percentile": 50, "Value": 0.0085}, {"Percentile": 99, "Value": 0.2}]
+  },
+  "RetCodes": {"200": 990, "503": 6, "-1": 4},
+  "Sizes": {"Count": 1000, "Sum": 512000},
+  "URL": "http://localhost:8080/checkout"
+}`
+
+	var result FortioResult
+	if err := json.Unmarshal([]byte(contents), &result); err != nil {
+		t.Fatal(err)
+	}
+
+	summaryData := TranslateFortioSummary(result, FortioLabel(result))
+	summary := summaryData.Summary
+	if summary.TotalRequests != 1000 || summary.TotalFailures != 10 || summary.MaxVirtualUsers != 8 || summary.Throughput.TotalBytesReceived != 512000 {
+		t.Error("Failed to translate totals: ", summary.TotalRequests, summary.TotalFailures, summary.MaxVirtualUsers, summary.Throughput)
+	}
+
+	// p95 isn't reported, so it comes from the histogram
+	if summary.Latencies.P50Ms != 8.5 || summary.Latencies.P99Ms != 200 || summary.Latencies.P95Ms != 50 || summary.Latencies.MaxMs != 350 {
+		t.Error("Failed to translate latencies: ", summary.Latencies)
+	}
+
+	if summaryData.StoppedAt-summaryData.StartedAt != 10000 || summary.Throughput.MeanRequestsPerSecond != 99.5 {
+		t.Error("Failed to translate run: ", summaryData.StartedAt, summaryData.StoppedAt, summary.Throughput.MeanRequestsPerSecond)
+	}
+
+	if labelSummary, ok := summaryData.SummaryByLabel["http://localhost:8080/checkout"]; !ok || labelSummary.Latencies.P99Ms != 200 {
+		t.Error("Failed to summarize label: ", summaryData.SummaryByLabel)
+	}
+}
+
+func TestTranslateTsungRequest(t *testing.T) {
+	indices, err := BuildColumnIndicesTsungDump(strings.Split("#date;pid;id;http method;host;URL;HTTP status;size;duration;transaction;match;error;tag", ";"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request, err := ParseTsungDumpRow(strings.Split("1647511212.456597;<0.134.0>;1;get;127.0.0.1;/checkout;503;512;41.923;-;-;-;-", ";"), indices)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	row := TranslateTsungRequest(request)
+	if row.TimeStamp != 1647511212456 || row.Latency != 41 || row.Label != "GET /checkout" || row.Failures != 1 || row.BytesReceived != 512 {
+		t.Error("Failed to translate request: ", row)
+	}
+}
+
+func TestTranslateTsungStats(t *testing.T) {
+	log := `# stats: dump at 1647511222
+stats: users 10 10
+stats: request 145 34.5 4.2 101.0 27.6 34.7 145
+stats: tr_login 10 120.5 8.1 180.0 90.2 120.5 10
+stats: size_rcv 74240 74240
+stats: 200 140 140
+stats: 404 5 5
+stats: error_connect_econnrefused 2 2
+`
+
+	dumps, err := ParseTsungLog(strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dumps) != 1 {
+		t.Fatal("Failed to read dumps: ", dumps)
+	}
+
+	buckets := TranslateTsungStats(dumps[0], tsungStatsInterval)
+	if len(buckets) != 2 {
+		t.Fatal("Expected overall and transaction buckets, got: ", buckets)
+	}
+
+	overall := buckets[0]
+	if overall.TimeStamp != 1647511212000 || overall.Requests != 147 || overall.Failures != 7 || overall.VirtualUsers != 10 || overall.BytesReceived != 74240 {
+		t.Error("Failed to translate interval: ", overall)
+	}
+	if overall.Latencies.AvgMs != 34.5 || overall.Latencies.MaxMs != 101 || overall.Latencies.MinMs != 27.6 {
+		t.Error("Failed to translate latencies: ", overall.Latencies)
+	}
+	if overall.Latencies.Unreported != AllPercentiles {
+		t.Error("Failed to leave out percentiles tsung.log doesn't have: ", overall.Latencies.Unreported)
+	}
+
+	if transaction := buckets[1]; transaction.Label != "login" || !transaction.Transaction || transaction.Requests != 10 {
+		t.Error("Failed to translate transaction: ", transaction)
+	}
+}
+
+func TestTranslateNBomberStats(t *testing.T) {
+	indices, err := BuildColumnIndicesNBomber(strings.Split("test_suite,test_name,scenario,duration,step_name,request_count,ok,failed,rps,min,mean,max,50_percent,75_percent,95_percent,99_percent,std_dev,data_transfer_min_kb,data_transfer_mean_kb,data_transfer_max_kb,data_transfer_all_mb", ","))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stats []NBomberStepStats
+	for _, row := range []string{
+		"nbomber,checkout,checkout_flow,00:00:30,login,600,598,2,20,1.2,30,410.8,28.1,40.2,80.5,120.3,12.1,0.5,1.2,2.0,1",
+		"nbomber,checkout,checkout_flow,00:01:00,login,1200,1195,5,20,1.2,35,410.8,30.1,42.2,80.5,120.3,12.1,0.5,1.2,2.0,3",
+	} {
+		step, err := ParseNBomberCsvRow(strings.Split(row, ","), indices)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stats = append(stats, step)
+	}
+
+	first, totals := TranslateNBomberStats(stats[0], 1647511212000, NBomberTotals{})
+	second, _ := TranslateNBomberStats(stats[1], 1647511212000, totals)
+
+	if first.Label != "checkout_flow / login" || first.Requests != 600 || first.Duration != 30*time.Second {
+		t.Error("Failed to translate first period: ", first)
+	}
+
+	if second.TimeStamp != 1647511242000 || second.Requests != 600 || second.Failures != 3 || second.BytesReceived != 2*1024*1024 {
+		t.Error("Failed to translate second period: ", second)
+	}
+
+	// (1195 * 35 - 598 * 30) / 597
+	if second.Latencies.AvgMs != 40.01 || second.Latencies.P95Ms != 80.5 {
+		t.Error("Failed to translate latencies: ", second.Latencies)
+	}
+	if second.Latencies.Unreported != Percentile90 {
+		t.Error("Failed to leave out p90: ", second.Latencies.Unreported)
+	}
+
+	// percentile columns are optional
+	indices, err = BuildColumnIndicesNBomber(strings.Split("test_suite,test_name,scenario,duration,step_name,request_count,ok,failed,rps,min,mean,max,50_percent", ","))
+	if err != nil {
+		t.Fatal(err)
+	}
+	step, err := ParseNBomberCsvRow(strings.Split("nbomber,checkout,checkout_flow,00:00:30,login,600,598,2,20,1.2,30,410.8,28.1", ","), indices)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if step.Latencies.Unreported != AllPercentiles&^Percentile50 {
+		t.Error("Failed to leave out missing percentile columns: ", step.Latencies.Unreported)
+	}
+}
+
+func TestParseTimeSpan(t *testing.T) {
+	tests := map[string]time.Duration{
+		"00:01:30":       90 * time.Second,
+		"01:00:00.5":     time.Hour + 500*time.Millisecond,
+		"1.02:00:00":     26 * time.Hour,
+		"00:00:05.25000": 5250 * time.Millisecond,
+	}
+
+	for value, expected := range tests {
+		duration, err := ParseTimeSpan(value)
+		if err != nil || duration != expected {
+			t.Error("Failed to parse ", value, ": ", duration, err)
+		}
 	}
 }
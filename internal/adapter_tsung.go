@@ -0,0 +1,274 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tsungDumpColumns is the layout of tsung.dump, written with
+// dumptraffic="protocol", when the file has no header. Tsung 1.5 added tag.
+var tsungDumpColumns = []string{"date", "pid", "id", "http method", "host", "URL", "HTTP status", "size", "duration", "transaction", "match", "error", "tag"}
+
+// TsungRequest is a request in tsung.dump. Date is in seconds and duration in
+// milliseconds.
+type TsungRequest struct {
+	Date     float64
+	Pid      string
+	Method   string
+	URL      string
+	Status   int
+	Size     uint64
+	Duration float64
+	Error    string
+}
+
+// BuildColumnIndicesTsungDump reads the header of tsung.dump, eg.
+// #date;pid;id;http method;host;URL;HTTP status;size;duration;transaction;match;error;tag
+func BuildColumnIndicesTsungDump(row []string) (map[string]int, error) {
+	indices := make(map[string]int)
+	for i, header := range row {
+		indices[strings.TrimPrefix(header, "#")] = i
+	}
+
+	missing := []string{}
+	for _, column := range []string{"date", "pid", "http method", "URL", "HTTP status", "duration"} {
+		if _, ok := indices[column]; !ok {
+			missing = append(missing, column)
+		}
+	}
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing column(s): %s", strings.Join(missing, ", "))
+	}
+
+	return indices, nil
+}
+
+func buildDefaultColumnIndicesTsungDump() map[string]int {
+	indices, _ := BuildColumnIndicesTsungDump(tsungDumpColumns)
+	return indices
+}
+
+func ParseTsungDumpRow(row []string, indices map[string]int) (TsungRequest, error) {
+	column := func(name string) string {
+		index, ok := indices[name]
+		if !ok || index >= len(row) {
+			return ""
+		}
+		return row[index]
+	}
+
+	date, err := strconv.ParseFloat(column("date"), 64)
+	if err != nil {
+		return TsungRequest{}, fmt.Errorf("failed to parse date: %v", err)
+	}
+
+	duration, err := strconv.ParseFloat(column("duration"), 64)
+	if err != nil {
+		return TsungRequest{}, fmt.Errorf("failed to parse duration: %v", err)
+	}
+
+	request := TsungRequest{
+		Date:     date,
+		Pid:      column("pid"),
+		Method:   strings.ToUpper(column("http method")),
+		URL:      column("URL"),
+		Duration: duration,
+	}
+
+	// status and size are "-" when the request failed without a response
+	request.Status, _ = strconv.Atoi(column("HTTP status"))
+	request.Size, _ = strconv.ParseUint(column("size"), 10, 64)
+	if message := column("error"); message != "-" {
+		request.Error = message
+	}
+
+	return request, nil
+}
+
+func TsungLabel(request TsungRequest) string {
+	return request.Method + " " + request.URL
+}
+
+func tsungFailed(request TsungRequest) bool {
+	return request.Error != "" || request.Status == 0 || request.Status >= 400
+}
+
+// tsungStart is the time stamp of a request in milliseconds. Tsung dumps the
+// time the request was sent.
+func tsungStart(request TsungRequest) uint64 {
+	return uint64(request.Date * 1000)
+}
+
+func TranslateTsungRequest(request TsungRequest) UngroupedMetricDataPoint {
+	parsed := UngroupedMetricDataPoint{
+		Requests:       1,
+		TimeStamp:      tsungStart(request),
+		Latency:        uint64(request.Duration),
+		Label:          TsungLabel(request),
+		ThreadName:     request.Pid,
+		FailureMessage: request.Error,
+		BytesReceived:  request.Size,
+	}
+
+	if request.Status > 0 {
+		parsed.ResponseCode = strconv.Itoa(request.Status)
+	}
+
+	if tsungFailed(request) {
+		parsed.Failures = 1
+	}
+
+	return parsed
+}
+
+func TranslateTsungSample(request TsungRequest) LingoSample {
+	return LingoSample{
+		TimeStamp:      tsungStart(request),
+		Label:          TsungLabel(request),
+		Elapsed:        uint64(request.Duration),
+		ResponseCode:   request.Status,
+		ThreadName:     request.Pid,
+		Success:        !tsungFailed(request),
+		FailureMessage: request.Error,
+		Bytes:          int(request.Size),
+		URL:            request.URL,
+	}
+}
+
+// TsungSampleStats is a sample line of tsung.log, eg. request or a
+// transaction. Count and mean cover the interval, max and min the run so far.
+type TsungSampleStats struct {
+	Count uint64
+	Mean  float64
+	Max   float64
+	Min   float64
+}
+
+// TsungStats holds a dump of tsung.log, written at TimeStamp (ms) for the
+// interval since the previous dump.
+type TsungStats struct {
+	TimeStamp uint64
+	Users     uint64
+	Samples   map[string]TsungSampleStats
+	// Counters hold the interval value of counters, eg. size_rcv, status
+	// codes and error_ counters.
+	Counters map[string]uint64
+}
+
+// tsungStatsInterval is how often Tsung dumps stats by default.
+const tsungStatsInterval = 10 * time.Second
+
+// ParseTsungLog reads the dumps in tsung.log:
+//
+//	# stats: dump at 1647511222
+//	stats: users 10 10
+//	stats: request 145 34.5 4.2 101.0 27.6 34.7 145
+//	stats: 200 140 140
+func ParseTsungLog(reader io.Reader) ([]TsungStats, error) {
+	var dumps []TsungStats
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if strings.HasPrefix(line, "# stats: dump at ") {
+			seconds, err := strconv.ParseUint(strings.TrimPrefix(line, "# stats: dump at "), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse dump time: %v", err)
+			}
+			dumps = append(dumps, TsungStats{
+				TimeStamp: seconds * 1000,
+				Samples:   make(map[string]TsungSampleStats),
+				Counters:  make(map[string]uint64),
+			})
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(dumps) == 0 || len(fields) < 3 || fields[0] != "stats:" {
+			continue
+		}
+
+		dump := &dumps[len(dumps)-1]
+		name, values := fields[1], fields[2:]
+		switch {
+		case name == "users":
+			dump.Users, _ = strconv.ParseUint(values[0], 10, 64)
+		case len(values) >= 5:
+			var sample TsungSampleStats
+			sample.Count, _ = strconv.ParseUint(values[0], 10, 64)
+			sample.Mean, _ = strconv.ParseFloat(values[1], 64)
+			sample.Max, _ = strconv.ParseFloat(values[3], 64)
+			sample.Min, _ = strconv.ParseFloat(values[4], 64)
+			dump.Samples[name] = sample
+		default:
+			value, _ := strconv.ParseFloat(values[0], 64)
+			dump.Counters[name] = uint64(value)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return dumps, nil
+}
+
+// TranslateTsungStats turns a dump into an overall bucket, and a bucket per
+// transaction. Errors, eg. error_connect_econnrefused, are requests that got
+// no response, so they count as requests and failures.
+func TranslateTsungStats(stats TsungStats, duration time.Duration) []AggregatedBucket {
+	request := stats.Samples["request"]
+	bucket := AggregatedBucket{
+		TimeStamp:     stats.TimeStamp - uint64(duration.Milliseconds()),
+		Duration:      duration,
+		Requests:      request.Count,
+		VirtualUsers:  stats.Users,
+		BytesReceived: stats.Counters["size_rcv"],
+		BytesSent:     stats.Counters["size_sent"],
+		Latencies:     tsungLatencies(request),
+	}
+
+	for name, count := range stats.Counters {
+		if strings.HasPrefix(name, "error_") {
+			bucket.Requests += count
+			bucket.Failures += count
+		} else if status, err := strconv.Atoi(name); err == nil && status >= 400 {
+			bucket.Failures += count
+		}
+	}
+
+	buckets := []AggregatedBucket{bucket}
+	for name, sample := range stats.Samples {
+		if !strings.HasPrefix(name, "tr_") {
+			continue
+		}
+		buckets = append(buckets, AggregatedBucket{
+			Label:        strings.TrimPrefix(name, "tr_"),
+			TimeStamp:    bucket.TimeStamp,
+			Duration:     duration,
+			Requests:     sample.Count,
+			VirtualUsers: stats.Users,
+			Latencies:    tsungLatencies(sample),
+			Transaction:  true,
+		})
+	}
+
+	return buckets
+}
+
+func tsungLatencies(sample TsungSampleStats) *Latencies {
+	if sample.Count == 0 {
+		return &Latencies{}
+	}
+
+	// tsung.log only has the mean, min and max
+	latencies := &Latencies{AvgMs: sample.Mean, MinMs: sample.Min, MaxMs: sample.Max, Unreported: AllPercentiles}
+	roundLatencies(latencies)
+	return latencies
+}
@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// VegetaResult is a vegeta attack result. Field names match vegeta.Result so
+// gob encoded results decode into it.
+type VegetaResult struct {
+	Attack    string        `json:"attack"`
+	Seq       uint64        `json:"seq"`
+	Code      uint16        `json:"code"`
+	Timestamp time.Time     `json:"timestamp"`
+	Latency   time.Duration `json:"latency"`
+	BytesOut  uint64        `json:"bytes_out"`
+	BytesIn   uint64        `json:"bytes_in"`
+	Error     string        `json:"error"`
+	Method    string        `json:"method"`
+	URL       string        `json:"url"`
+}
+
+// Vegeta --label-by values. Requests are labeled by method and URL by default.
+const (
+	VegetaLabelByMethodURL = "method-url"
+	VegetaLabelByURL       = "url"
+	VegetaLabelByAttack    = "attack"
+)
+
+// ParseVegetaCsvRow reads a row of vegeta's CSV encoding, which has no header:
+// timestamp (ns), code, latency (ns), bytes out, bytes in, error, base64 body,
+// attack, seq, method, url and base64 headers. Older versions stop after seq.
+func ParseVegetaCsvRow(row []string) (VegetaResult, error) {
+	if len(row) < 6 {
+		return VegetaResult{}, fmt.Errorf("expected at least 6 columns, got %d", len(row))
+	}
+
+	var (
+		result VegetaResult
+		values [5]uint64
+	)
+	for i := range values {
+		value, err := strconv.ParseUint(row[i], 10, 64)
+		if err != nil {
+			return VegetaResult{}, fmt.Errorf("failed to parse column %d: %v", i+1, err)
+		}
+		values[i] = value
+	}
+
+	result.Timestamp = time.Unix(0, int64(values[0]))
+	result.Code = uint16(values[1])
+	result.Latency = time.Duration(values[2])
+	result.BytesOut = values[3]
+	result.BytesIn = values[4]
+	result.Error = row[5]
+
+	if len(row) > 8 {
+		result.Attack = row[7]
+		result.Seq, _ = strconv.ParseUint(row[8], 10, 64)
+	}
+	if len(row) > 10 {
+		result.Method = row[9]
+		result.URL = row[10]
+	}
+
+	return result, nil
+}
+
+func VegetaLabel(result VegetaResult, labelBy string) string {
+	switch labelBy {
+	case VegetaLabelByURL:
+		return result.URL
+	case VegetaLabelByAttack:
+		return result.Attack
+	}
+
+	if result.Method == "" && result.URL == "" {
+		// results from vegeta versions before method and url were recorded
+		return result.Attack
+	}
+	if result.Method == "" {
+		return result.URL
+	}
+	return result.Method + " " + result.URL
+}
+
+// vegetaFailed follows vegeta's own success ratio, which counts 2xx and 3xx
+// responses without an error.
+func vegetaFailed(result VegetaResult) bool {
+	return result.Error != "" || result.Code < 200 || result.Code >= 400
+}
+
+func TranslateVegetaRow(result VegetaResult, labelBy string) UngroupedMetricDataPoint {
+	parsed := UngroupedMetricDataPoint{
+		Requests:       1,
+		TimeStamp:      uint64(result.Timestamp.UnixMilli()),
+		Latency:        uint64(result.Latency.Milliseconds()),
+		Label:          VegetaLabel(result, labelBy),
+		ResponseCode:   strconv.Itoa(int(result.Code)),
+		FailureMessage: result.Error,
+		BytesReceived:  result.BytesIn,
+		BytesSent:      result.BytesOut,
+	}
+
+	if vegetaFailed(result) {
+		parsed.Failures = 1
+	}
+
+	return parsed
+}
+
+func TranslateVegetaSample(result VegetaResult, labelBy string) LingoSample {
+	return LingoSample{
+		TimeStamp:      uint64(result.Timestamp.UnixMilli()),
+		Label:          VegetaLabel(result, labelBy),
+		Elapsed:        uint64(result.Latency.Milliseconds()),
+		ResponseCode:   int(result.Code),
+		ThreadName:     result.Attack,
+		Success:        !vegetaFailed(result),
+		FailureMessage: result.Error,
+		Bytes:          int(result.BytesIn),
+		SentBytes:      int(result.BytesOut),
+		URL:            result.URL,
+	}
+}
@@ -0,0 +1,199 @@
+package internal
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/montanaflynn/stats"
+)
+
+// WrkResult holds the text output of wrk and wrk2 run with --latency.
+// Percentiles are keyed by percent, eg. 99.9.
+type WrkResult struct {
+	URL            string
+	Duration       time.Duration
+	Connections    uint64
+	Requests       uint64
+	Errors         uint64
+	BytesRead      uint64
+	RequestsPerSec float64
+	TransferPerSec float64
+	MeanMs         float64
+	MaxMs          float64
+	MinMs          float64
+	Percentiles    map[float64]float64
+}
+
+var (
+	wrkRunningPattern     = regexp.MustCompile(`^Running (\S+) test @ (\S+)`)
+	wrkConnectionsPattern = regexp.MustCompile(`(\d+) threads and (\d+) connections`)
+	wrkLatencyPattern     = regexp.MustCompile(`^Latency\s+(\d\S*)\s+\S+\s+(\d\S*)`)
+	// wrk writes "50%  1.06ms", wrk2 "50.000%    1.06ms"
+	wrkPercentilePattern = regexp.MustCompile(`^(\d+(?:\.\d+)?)%\s+(\S+)$`)
+	// wrk2 percentile spectrum rows: value (ms), percentile, total count, 1/(1-percentile)
+	wrkSpectrumPattern   = regexp.MustCompile(`^(\d+\.\d+)\s+(\d\.\d+)\s+(\d+)\s+\S+$`)
+	wrkMeanPattern       = regexp.MustCompile(`^#\[Mean\s+=\s+(\S+),`)
+	wrkMaxPattern        = regexp.MustCompile(`^#\[Max\s+=\s+(\S+),`)
+	wrkRequestsPattern   = regexp.MustCompile(`^(\d+) requests in (\S+), (\S+) read`)
+	wrkSocketPattern     = regexp.MustCompile(`^Socket errors: connect (\d+), read (\d+), write (\d+), timeout (\d+)`)
+	wrkNon2xxPattern     = regexp.MustCompile(`^Non-2xx or 3xx responses: (\d+)`)
+	wrkThroughputPattern = regexp.MustCompile(`^Requests/sec:\s+(\S+)`)
+	wrkTransferPattern   = regexp.MustCompile(`^Transfer/sec:\s+(\S+)`)
+)
+
+func ParseWrkOutput(reader io.Reader) (*WrkResult, error) {
+	result := &WrkResult{Percentiles: make(map[float64]float64)}
+	spectrum := false
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if match := wrkRunningPattern.FindStringSubmatch(line); match != nil {
+			result.URL = match[2]
+			result.Duration = parseWrkDuration(match[1])
+		} else if match := wrkConnectionsPattern.FindStringSubmatch(line); match != nil {
+			result.Connections, _ = strconv.ParseUint(match[2], 10, 64)
+		} else if match := wrkLatencyPattern.FindStringSubmatch(line); match != nil {
+			result.MeanMs = parseWrkMillis(match[1])
+			result.MaxMs = parseWrkMillis(match[2])
+		} else if strings.HasPrefix(line, "Detailed Percentile spectrum") {
+			spectrum = true
+		} else if match := wrkPercentilePattern.FindStringSubmatch(line); match != nil && !spectrum {
+			percent, _ := strconv.ParseFloat(match[1], 64)
+			result.Percentiles[percent] = parseWrkMillis(match[2])
+		} else if match := wrkSpectrumPattern.FindStringSubmatch(line); match != nil && spectrum {
+			addWrkSpectrumRow(result, match)
+		} else if match := wrkMeanPattern.FindStringSubmatch(line); match != nil {
+			result.MeanMs, _ = strconv.ParseFloat(match[1], 64)
+		} else if match := wrkMaxPattern.FindStringSubmatch(line); match != nil {
+			result.MaxMs, _ = strconv.ParseFloat(match[1], 64)
+			spectrum = false
+		} else if match := wrkRequestsPattern.FindStringSubmatch(line); match != nil {
+			result.Requests, _ = strconv.ParseUint(match[1], 10, 64)
+			result.Duration = parseWrkDuration(match[2])
+			result.BytesRead = parseWrkBytes(match[3])
+		} else if match := wrkSocketPattern.FindStringSubmatch(line); match != nil {
+			for _, count := range match[1:] {
+				errors, _ := strconv.ParseUint(count, 10, 64)
+				result.Errors += errors
+			}
+		} else if match := wrkNon2xxPattern.FindStringSubmatch(line); match != nil {
+			errors, _ := strconv.ParseUint(match[1], 10, 64)
+			result.Errors += errors
+		} else if match := wrkThroughputPattern.FindStringSubmatch(line); match != nil {
+			result.RequestsPerSec, _ = strconv.ParseFloat(match[1], 64)
+		} else if match := wrkTransferPattern.FindStringSubmatch(line); match != nil {
+			result.TransferPerSec = float64(parseWrkBytes(match[1]))
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// addWrkSpectrumRow keeps the first value at or above each percentile that
+// isn't in the latency distribution, eg. p95, and the min.
+func addWrkSpectrumRow(result *WrkResult, match []string) {
+	value, _ := strconv.ParseFloat(match[1], 64)
+	quantile, _ := strconv.ParseFloat(match[2], 64)
+
+	if result.MinMs == 0 {
+		result.MinMs = value
+	}
+	for _, percent := range []float64{75, 95} {
+		if _, ok := result.Percentiles[percent]; !ok && quantile*100 >= percent {
+			result.Percentiles[percent] = value
+		}
+	}
+}
+
+func parseWrkDuration(value string) time.Duration {
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0
+	}
+	return duration
+}
+
+func parseWrkMillis(value string) float64 {
+	return float64(parseWrkDuration(value).Microseconds()) / 1000
+}
+
+// parseWrkBytes reads sizes like 21.46MB, wrk uses binary units.
+func parseWrkBytes(value string) uint64 {
+	units := []struct {
+		suffix     string
+		multiplier float64
+	}{
+		{"TB", 1 << 40},
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"B", 1},
+	}
+
+	for _, unit := range units {
+		if strings.HasSuffix(value, unit.suffix) {
+			number, err := strconv.ParseFloat(strings.TrimSuffix(value, unit.suffix), 64)
+			if err != nil {
+				return 0
+			}
+			return uint64(number * unit.multiplier)
+		}
+	}
+	return 0
+}
+
+// TranslateWrkResult maps the output to a summary. wrk only reports totals,
+// so the run has no chart metrics. Percentiles missing from the output, eg. p95
+// without the wrk2 percentile spectrum, are left out.
+func TranslateWrkResult(result *WrkResult) MetricSummary {
+	summary := MetricSummary{
+		Latencies: &Latencies{
+			AvgMs: result.MeanMs,
+			MinMs: result.MinMs,
+			MaxMs: result.MaxMs,
+			P50Ms: result.Percentiles[50],
+			P75Ms: result.Percentiles[75],
+			P90Ms: result.Percentiles[90],
+			P95Ms: result.Percentiles[95],
+			P99Ms: result.Percentiles[99],
+		},
+		TotalRequests:   result.Requests,
+		TotalFailures:   result.Errors,
+		MaxVirtualUsers: result.Connections,
+		Throughput: &ThroughputSummary{
+			MeanRequestsPerSecond:      result.RequestsPerSec,
+			MeanBytesReceivedPerSecond: result.TransferPerSec,
+			TotalBytesReceived:         result.BytesRead,
+		},
+	}
+
+	if result.Requests > 0 {
+		summary.Throughput.AvgResponseBytes = float64(result.BytesRead) / float64(result.Requests)
+	}
+
+	for percent, percentile := range map[float64]Percentiles{
+		50: Percentile50,
+		75: Percentile75,
+		90: Percentile90,
+		95: Percentile95,
+		99: Percentile99,
+	} {
+		if _, ok := result.Percentiles[percent]; !ok {
+			summary.Latencies.Unreported |= percentile
+		}
+	}
+
+	roundLatencies(summary.Latencies)
+	summary.Throughput.AvgResponseBytes, _ = stats.Round(summary.Throughput.AvgResponseBytes, 2)
+	return summary
+}
@@ -0,0 +1,440 @@
+package internal
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/montanaflynn/stats"
+)
+
+// AggregatedBucket holds requests the load test tool already aggregated over
+// a period, eg. an Artillery period or a Locust stats history row. Buckets
+// without a label hold overall metrics, when a format reports none they are
+// rolled up from the labeled buckets. Time stamps are in milliseconds.
+type AggregatedBucket struct {
+	Label         string
+	TimeStamp     uint64
+	Duration      time.Duration
+	Requests      uint64
+	Failures      uint64
+	VirtualUsers  uint64
+	BytesReceived uint64
+	BytesSent     uint64
+	// Latencies are kept as reported. Percentiles the tool doesn't report are
+	// zero.
+	Latencies *Latencies
+	// Histogram is set when the tool reports the latency distribution, so
+	// percentiles can be merged accurately.
+	Histogram   []HistogramBin
+	Transaction bool
+	// Generator is the load generator that reported the bucket, virtual users
+	// are summed across generators.
+	Generator string
+}
+
+// HistogramBin counts the requests with a latency up to UpperBoundMs, and
+// above the upper bound of the previous bin.
+type HistogramBin struct {
+	UpperBoundMs float64
+	Count        uint64
+}
+
+// GroupAggregatedBuckets rolls buckets up to the time aggregation levels of
+// the run, starting with the buckets' own period. Rolling up is exact for
+// counts, min and max, and request weighted for the average. Percentiles are
+// merged from histograms when every bucket has one, otherwise they are the
+// request weighted mean of the bucket percentiles, which is close for steady
+// load but smooths out short spikes.
+func GroupAggregatedBuckets(buckets []AggregatedBucket) GroupedResult {
+	groupedResult := GroupedResult{
+		DataPointsByLabel:     make(map[string][]MetricDataPoint),
+		DataPointsByGenerator: make(map[string][]MetricDataPoint),
+	}
+	if len(buckets) == 0 {
+		return groupedResult
+	}
+
+	overall, byLabel := splitAggregatedBuckets(buckets)
+	for _, level := range aggregatedTimeAggregationLevels(buckets) {
+		groupedResult.DataPoints = append(groupedResult.DataPoints, rollUpAggregatedSeries(overall, "", level)...)
+		for label, labelBuckets := range byLabel {
+			groupedResult.DataPointsByLabel[label] = append(groupedResult.DataPointsByLabel[label], rollUpAggregatedSeries(labelBuckets, label, level)...)
+		}
+	}
+
+	return groupedResult
+}
+
+// SummarizeAggregatedBuckets rolls all buckets up into run summaries, with
+// the same approximations as GroupAggregatedBuckets.
+func SummarizeAggregatedBuckets(buckets []AggregatedBucket) (MetricSummary, map[string]MetricSummary) {
+	overall, byLabel := splitAggregatedBuckets(buckets)
+
+	summaryByLabel := make(map[string]MetricSummary)
+	for label, labelBuckets := range byLabel {
+		summaryByLabel[label] = summarizeAggregatedBuckets(labelBuckets, label)
+	}
+
+	return summarizeAggregatedBuckets(overall, ""), summaryByLabel
+}
+
+// AggregatedBucketsRange returns the start of the first bucket and the end of
+// the last one.
+func AggregatedBucketsRange(buckets []AggregatedBucket) (uint64, uint64) {
+	var startedAt, stoppedAt uint64
+	for i, bucket := range buckets {
+		end := bucket.TimeStamp + uint64(bucket.Duration.Milliseconds())
+		if i == 0 || bucket.TimeStamp < startedAt {
+			startedAt = bucket.TimeStamp
+		}
+		if end > stoppedAt {
+			stoppedAt = end
+		}
+	}
+	return startedAt, stoppedAt
+}
+
+func splitAggregatedBuckets(buckets []AggregatedBucket) ([]AggregatedBucket, map[string][]AggregatedBucket) {
+	var (
+		overall []AggregatedBucket
+		labeled []AggregatedBucket
+		byLabel = make(map[string][]AggregatedBucket)
+		sorted  = make([]AggregatedBucket, len(buckets))
+	)
+	copy(sorted, buckets)
+	sort.SliceStable(sorted, func(i int, j int) bool {
+		return sorted[i].TimeStamp < sorted[j].TimeStamp
+	})
+
+	for _, bucket := range sorted {
+		if bucket.Label == "" {
+			overall = append(overall, bucket)
+			continue
+		}
+
+		byLabel[bucket.Label] = append(byLabel[bucket.Label], bucket)
+		// transactions group other requests, counting them overall would double count
+		if !bucket.Transaction {
+			labeled = append(labeled, bucket)
+		}
+	}
+
+	if len(overall) == 0 {
+		overall = labeled
+	}
+	return overall, byLabel
+}
+
+// aggregatedTimeAggregationLevels chooses levels as for requests, skipping
+// levels finer than the buckets. The buckets' own period comes first when it
+// isn't one of the levels, eg. Artillery's 10s periods, so the percentiles the
+// tool reported are published untouched, unless it would make too many
+// buckets.
+func aggregatedTimeAggregationLevels(buckets []AggregatedBucket) []TimeAggregationLevel {
+	period := aggregatedPeriod(buckets)
+	startedAt, stoppedAt := AggregatedBucketsRange(buckets)
+	duration := time.Duration(stoppedAt-startedAt) * time.Millisecond
+	levels := ChooseTimeAggregationLevels(duration, period)
+
+	for _, level := range candidateTimeAggregationLevels {
+		if level.Duration() == period {
+			return levels
+		}
+	}
+
+	if duration/period <= maxTimeAggregationPoints {
+		levels = append([]TimeAggregationLevel{periodTimeAggregationLevel(period)}, levels...)
+	}
+	return levels
+}
+
+// periodTimeAggregationLevel names a period like the built-in levels, eg.
+// "10s" or "2m" rather than "2m0s".
+func periodTimeAggregationLevel(period time.Duration) TimeAggregationLevel {
+	name := period.String()
+	if strings.HasSuffix(name, "m0s") {
+		name = strings.TrimSuffix(name, "0s")
+	}
+	if strings.HasSuffix(name, "h0m") {
+		name = strings.TrimSuffix(name, "0m")
+	}
+	return TimeAggregationLevel(name)
+}
+
+// aggregatedPeriod is the shortest bucket duration.
+func aggregatedPeriod(buckets []AggregatedBucket) time.Duration {
+	period := buckets[0].Duration
+	for _, bucket := range buckets {
+		if bucket.Duration < period {
+			period = bucket.Duration
+		}
+	}
+	if period <= 0 {
+		return time.Second
+	}
+	return period
+}
+
+// rollUpAggregatedSeries rolls sorted buckets up into aligned, half-open
+// buckets of the level. Buckets count towards the level bucket they start in.
+func rollUpAggregatedSeries(buckets []AggregatedBucket, label string, level TimeAggregationLevel) []MetricDataPoint {
+	var (
+		dataPoints []MetricDataPoint
+		batch      []AggregatedBucket
+		startTime  uint64
+	)
+	interval := level.Milliseconds()
+
+	for i, bucket := range buckets {
+		floor := calculateIntervalFloor(bucket.TimeStamp, interval)
+		if i == 0 {
+			startTime = floor
+		}
+
+		if floor != startTime {
+			dataPoints = appendDataPoint(dataPoints, rollUpAggregatedBuckets(batch, label, startTime, level))
+			batch = nil
+			startTime = floor
+		}
+		batch = append(batch, bucket)
+	}
+
+	if len(batch) > 0 {
+		dataPoints = appendDataPoint(dataPoints, rollUpAggregatedBuckets(batch, label, startTime, level))
+	}
+
+	return dataPoints
+}
+
+func rollUpAggregatedBuckets(buckets []AggregatedBucket, label string, startTime uint64, level TimeAggregationLevel) MetricDataPoint {
+	grouped := MetricDataPoint{
+		Label:                label,
+		TimeStamp:            startTime,
+		TimeAggregationLevel: level,
+		Latencies:            mergeAggregatedLatencies(buckets),
+	}
+
+	var bytesReceived, bytesSent uint64
+	virtualUsersPerGenerator := make(map[string]uint64)
+	for _, bucket := range buckets {
+		grouped.Requests += bucket.Requests
+		grouped.Failures += bucket.Failures
+		bytesReceived += bucket.BytesReceived
+		bytesSent += bucket.BytesSent
+		grouped.Transaction = bucket.Transaction
+		// virtual user counts are levels, not totals
+		if bucket.VirtualUsers > virtualUsersPerGenerator[bucket.Generator] {
+			virtualUsersPerGenerator[bucket.Generator] = bucket.VirtualUsers
+		}
+	}
+	for _, virtualUsers := range virtualUsersPerGenerator {
+		grouped.VirtualUsers += virtualUsers
+	}
+	grouped.Throughput = calculateThroughput(grouped.Requests, bytesReceived, bytesSent, level.Duration())
+
+	return grouped
+}
+
+func summarizeAggregatedBuckets(buckets []AggregatedBucket, label string) MetricSummary {
+	summary := MetricSummary{
+		Label:      label,
+		Latencies:  mergeAggregatedLatencies(buckets),
+		Throughput: &ThroughputSummary{},
+	}
+
+	for _, bucket := range buckets {
+		summary.TotalRequests += bucket.Requests
+		summary.TotalFailures += bucket.Failures
+		summary.Throughput.TotalBytesReceived += bucket.BytesReceived
+		summary.Throughput.TotalBytesSent += bucket.BytesSent
+		summary.Transaction = bucket.Transaction
+	}
+
+	if len(buckets) == 0 {
+		return summary
+	}
+
+	startedAt, stoppedAt := AggregatedBucketsRange(buckets)
+	mean := calculateThroughput(summary.TotalRequests, summary.Throughput.TotalBytesReceived, summary.Throughput.TotalBytesSent, time.Duration(stoppedAt-startedAt)*time.Millisecond)
+	summary.Throughput.MeanRequestsPerSecond = mean.RequestsPerSecond
+	summary.Throughput.MeanBytesReceivedPerSecond = mean.BytesReceivedPerSecond
+	summary.Throughput.MeanBytesSentPerSecond = mean.BytesSentPerSecond
+	summary.Throughput.AvgResponseBytes = mean.AvgResponseBytes
+
+	// peaks are taken from the buckets' own period
+	for _, dp := range rollUpAggregatedSeries(buckets, label, periodTimeAggregationLevel(aggregatedPeriod(buckets))) {
+		if dp.VirtualUsers > summary.MaxVirtualUsers {
+			summary.MaxVirtualUsers = dp.VirtualUsers
+		}
+		summary.Throughput.PeakRequestsPerSecond = math.Max(summary.Throughput.PeakRequestsPerSecond, dp.Throughput.RequestsPerSecond)
+		summary.Throughput.PeakBytesReceivedPerSecond = math.Max(summary.Throughput.PeakBytesReceivedPerSecond, dp.Throughput.BytesReceivedPerSecond)
+		summary.Throughput.PeakBytesSentPerSecond = math.Max(summary.Throughput.PeakBytesSentPerSecond, dp.Throughput.BytesSentPerSecond)
+	}
+
+	return summary
+}
+
+func mergeAggregatedLatencies(buckets []AggregatedBucket) *Latencies {
+	var (
+		merged       Latencies
+		requests     float64
+		hasHistogram = true
+		first        = true
+	)
+
+	for _, bucket := range buckets {
+		if bucket.Requests == 0 || bucket.Latencies == nil {
+			continue
+		}
+		hasHistogram = hasHistogram && len(bucket.Histogram) > 0
+
+		weight := float64(bucket.Requests)
+		requests += weight
+		merged.AvgMs += bucket.Latencies.AvgMs * weight
+		merged.P50Ms += bucket.Latencies.P50Ms * weight
+		merged.P75Ms += bucket.Latencies.P75Ms * weight
+		merged.P90Ms += bucket.Latencies.P90Ms * weight
+		merged.P95Ms += bucket.Latencies.P95Ms * weight
+		merged.P99Ms += bucket.Latencies.P99Ms * weight
+		// a percentile missing from any bucket can't be merged
+		merged.Unreported |= bucket.Latencies.Unreported
+
+		if first || bucket.Latencies.MinMs < merged.MinMs {
+			merged.MinMs = bucket.Latencies.MinMs
+		}
+		if bucket.Latencies.MaxMs > merged.MaxMs {
+			merged.MaxMs = bucket.Latencies.MaxMs
+		}
+		first = false
+	}
+
+	if requests == 0 {
+		return &Latencies{}
+	}
+
+	merged.AvgMs /= requests
+	merged.P50Ms /= requests
+	merged.P75Ms /= requests
+	merged.P90Ms /= requests
+	merged.P95Ms /= requests
+	merged.P99Ms /= requests
+
+	if hasHistogram {
+		histogram := mergeHistograms(buckets)
+		merged.P50Ms = histogramPercentile(histogram, 50, merged.MaxMs)
+		merged.P75Ms = histogramPercentile(histogram, 75, merged.MaxMs)
+		merged.P90Ms = histogramPercentile(histogram, 90, merged.MaxMs)
+		merged.P95Ms = histogramPercentile(histogram, 95, merged.MaxMs)
+		merged.P99Ms = histogramPercentile(histogram, 99, merged.MaxMs)
+		merged.Unreported = 0
+	}
+
+	roundLatencies(&merged)
+	return &merged
+}
+
+func mergeHistograms(buckets []AggregatedBucket) []HistogramBin {
+	counts := make(map[float64]uint64)
+	for _, bucket := range buckets {
+		if bucket.Requests == 0 {
+			continue
+		}
+		for _, bin := range bucket.Histogram {
+			counts[bin.UpperBoundMs] += bin.Count
+		}
+	}
+
+	var histogram []HistogramBin
+	for upperBound, count := range counts {
+		histogram = append(histogram, HistogramBin{UpperBoundMs: upperBound, Count: count})
+	}
+	sort.Slice(histogram, func(i int, j int) bool {
+		return histogram[i].UpperBoundMs < histogram[j].UpperBoundMs
+	})
+
+	return histogram
+}
+
+// histogramPercentile returns the upper bound of the bin holding the
+// percentile, capped at the max latency when it is known.
+func histogramPercentile(histogram []HistogramBin, percentile float64, maxMs float64) float64 {
+	var total uint64
+	for _, bin := range histogram {
+		total += bin.Count
+	}
+
+	rank := uint64(math.Ceil(float64(total) * percentile / 100))
+	var count uint64
+	for _, bin := range histogram {
+		count += bin.Count
+		if count >= rank {
+			value := bin.UpperBoundMs
+			if maxMs > 0 {
+				value = math.Min(value, maxMs)
+			}
+			value, _ = stats.Round(value, 2)
+			return value
+		}
+	}
+
+	return maxMs
+}
+
+// MergeMetricSummaries combines the summaries of labels that normalize to the
+// same label. Counts and totals are summed, min and max are exact, and the
+// average and percentiles are weighted by requests. Peaks may not coincide,
+// so the larger peak is kept.
+func MergeMetricSummaries(a MetricSummary, b MetricSummary) MetricSummary {
+	merged := MetricSummary{
+		Label:           a.Label,
+		Scope:           a.Scope,
+		TotalRequests:   a.TotalRequests + b.TotalRequests,
+		TotalFailures:   a.TotalFailures + b.TotalFailures,
+		MaxVirtualUsers: a.MaxVirtualUsers,
+		Latencies:       mergeSummaryLatencies(a.Latencies, b.Latencies, a.TotalRequests, b.TotalRequests),
+		Transaction:     a.Transaction && b.Transaction,
+	}
+	if b.MaxVirtualUsers > merged.MaxVirtualUsers {
+		merged.MaxVirtualUsers = b.MaxVirtualUsers
+	}
+
+	if a.Throughput == nil || b.Throughput == nil {
+		merged.Throughput = a.Throughput
+		if merged.Throughput == nil {
+			merged.Throughput = b.Throughput
+		}
+		return merged
+	}
+
+	merged.Throughput = &ThroughputSummary{
+		MeanRequestsPerSecond:      a.Throughput.MeanRequestsPerSecond + b.Throughput.MeanRequestsPerSecond,
+		PeakRequestsPerSecond:      math.Max(a.Throughput.PeakRequestsPerSecond, b.Throughput.PeakRequestsPerSecond),
+		MeanBytesReceivedPerSecond: a.Throughput.MeanBytesReceivedPerSecond + b.Throughput.MeanBytesReceivedPerSecond,
+		PeakBytesReceivedPerSecond: math.Max(a.Throughput.PeakBytesReceivedPerSecond, b.Throughput.PeakBytesReceivedPerSecond),
+		MeanBytesSentPerSecond:     a.Throughput.MeanBytesSentPerSecond + b.Throughput.MeanBytesSentPerSecond,
+		PeakBytesSentPerSecond:     math.Max(a.Throughput.PeakBytesSentPerSecond, b.Throughput.PeakBytesSentPerSecond),
+		TotalBytesReceived:         a.Throughput.TotalBytesReceived + b.Throughput.TotalBytesReceived,
+		TotalBytesSent:             a.Throughput.TotalBytesSent + b.Throughput.TotalBytesSent,
+	}
+	if merged.TotalRequests > 0 {
+		merged.Throughput.AvgResponseBytes, _ = stats.Round(float64(merged.Throughput.TotalBytesReceived)/float64(merged.TotalRequests), 2)
+	}
+
+	return merged
+}
+
+func mergeSummaryLatencies(a *Latencies, b *Latencies, aRequests uint64, bRequests uint64) *Latencies {
+	if b == nil || bRequests == 0 {
+		return a
+	}
+	if a == nil || aRequests == 0 {
+		return b
+	}
+
+	return mergeAggregatedLatencies([]AggregatedBucket{
+		{Requests: aRequests, Latencies: a},
+		{Requests: bRequests, Latencies: b},
+	})
+}
@@ -0,0 +1,163 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGroupAggregatedBuckets(t *testing.T) {
+	var buckets []AggregatedBucket
+	// 2 minutes of 10s buckets, alternating between 100 and 300 requests
+	for i := 0; i < 12; i++ {
+		requests, p95 := uint64(100), 200.0
+		if i%2 == 1 {
+			requests, p95 = 300, 400
+		}
+		buckets = append(buckets, AggregatedBucket{
+			Label:        "checkout",
+			TimeStamp:    1647453610000 + uint64(i)*10000,
+			Duration:     10 * time.Second,
+			Requests:     requests,
+			Failures:     1,
+			VirtualUsers: uint64(i),
+			Latencies:    &Latencies{AvgMs: 50, MinMs: float64(10 + i), MaxMs: float64(500 + i), P95Ms: p95},
+		})
+	}
+
+	grouped := GroupAggregatedBuckets(buckets)
+	dataPoints := grouped.DataPointsByLabel["checkout"]
+
+	// the buckets' own 10s level, then 30s
+	if len(dataPoints) != 17 || dataPoints[0].TimeAggregationLevel != "10s" || dataPoints[12].TimeAggregationLevel != ThirtySeconds {
+		t.Fatal("Failed to roll up levels: ", len(dataPoints), " expected: ", 17)
+	}
+
+	// the period's own percentiles are published as reported
+	if dataPoints[1].Latencies.P95Ms != 400 || dataPoints[1].Requests != 300 {
+		t.Error("Failed to keep reported percentiles: ", dataPoints[1].Latencies)
+	}
+
+	if len(grouped.DataPoints) != 17 || grouped.DataPoints[0].Requests != 100 {
+		t.Error("Failed to roll up overall buckets from labels: ", len(grouped.DataPoints))
+	}
+
+	// 30s buckets start at 1647453600000, so the first one holds 2 buckets
+	first := dataPoints[12]
+	if first.Requests != 400 || first.Failures != 2 || first.VirtualUsers != 1 {
+		t.Error("Failed to sum counts: ", first.Requests, first.Failures, first.VirtualUsers)
+	}
+
+	if first.Latencies.P95Ms != 350 || first.Latencies.MinMs != 10 || first.Latencies.MaxMs != 501 || first.Latencies.AvgMs != 50 {
+		t.Error("Failed to merge latencies: ", first.Latencies)
+	}
+
+	summary, summaryByLabel := SummarizeAggregatedBuckets(buckets)
+	if summary.TotalRequests != 2400 || summary.MaxVirtualUsers != 11 || summaryByLabel["checkout"].Latencies.MaxMs != 511 {
+		t.Error("Failed to summarize buckets: ", summary.TotalRequests, summary.MaxVirtualUsers)
+	}
+
+	if summary.Throughput.MeanRequestsPerSecond != 20 || summary.Throughput.PeakRequestsPerSecond != 30 {
+		t.Error("Failed to summarize throughput: ", summary.Throughput)
+	}
+}
+
+func TestMergeAggregatedLatenciesFromHistograms(t *testing.T) {
+	buckets := []AggregatedBucket{
+		{
+			Requests:  10,
+			Latencies: &Latencies{MinMs: 1, MaxMs: 45, P50Ms: 5, P99Ms: 45},
+			Histogram: []HistogramBin{{UpperBoundMs: 10, Count: 9}, {UpperBoundMs: 50, Count: 1}},
+		},
+		{
+			Requests:  10,
+			Latencies: &Latencies{MinMs: 20, MaxMs: 90, P50Ms: 40, P99Ms: 90},
+			Histogram: []HistogramBin{{UpperBoundMs: 50, Count: 8}, {UpperBoundMs: 100, Count: 2}},
+		},
+	}
+
+	latencies := mergeAggregatedLatencies(buckets)
+	if latencies.P50Ms != 50 || latencies.P75Ms != 50 || latencies.P99Ms != 90 {
+		t.Error("Failed to merge histograms: ", latencies)
+	}
+
+	// without histograms, percentiles are request weighted
+	buckets[1].Histogram = nil
+	latencies = mergeAggregatedLatencies(buckets)
+	if latencies.P50Ms != 22.5 || latencies.MinMs != 1 || latencies.MaxMs != 90 {
+		t.Error("Failed to merge percentiles: ", latencies)
+	}
+}
+
+func TestMergeMetricSummaries(t *testing.T) {
+	a := MetricSummary{
+		Label:         "GET /orders/{id}",
+		TotalRequests: 100,
+		TotalFailures: 1,
+		Latencies:     &Latencies{AvgMs: 10, MinMs: 2, MaxMs: 50, P95Ms: 30},
+		Throughput:    &ThroughputSummary{MeanRequestsPerSecond: 10, PeakRequestsPerSecond: 20, TotalBytesReceived: 1000},
+	}
+	b := MetricSummary{
+		Label:         "GET /orders/{id}",
+		TotalRequests: 300,
+		TotalFailures: 2,
+		Latencies:     &Latencies{AvgMs: 30, MinMs: 1, MaxMs: 90, P95Ms: 70},
+		Throughput:    &ThroughputSummary{MeanRequestsPerSecond: 30, PeakRequestsPerSecond: 40, TotalBytesReceived: 3000},
+	}
+
+	merged := MergeMetricSummaries(a, b)
+	if merged.TotalRequests != 400 || merged.TotalFailures != 3 {
+		t.Error("Failed to sum counts: ", merged.TotalRequests, merged.TotalFailures)
+	}
+
+	if merged.Latencies.AvgMs != 25 || merged.Latencies.P95Ms != 60 || merged.Latencies.MinMs != 1 || merged.Latencies.MaxMs != 90 {
+		t.Error("Failed to merge latencies: ", merged.Latencies)
+	}
+
+	if merged.Throughput.MeanRequestsPerSecond != 40 || merged.Throughput.PeakRequestsPerSecond != 40 || merged.Throughput.AvgResponseBytes != 10 {
+		t.Error("Failed to merge throughput: ", merged.Throughput)
+	}
+}
+
+func TestGroupAggregatedBucketsSumsVirtualUsersAcrossGenerators(t *testing.T) {
+	var buckets []AggregatedBucket
+	for i := 0; i < 60; i++ {
+		for _, generator := range []string{"a.csv", "b.csv"} {
+			buckets = append(buckets, AggregatedBucket{
+				TimeStamp:    1647453610000 + uint64(i)*1000,
+				Duration:     time.Second,
+				Requests:     10,
+				VirtualUsers: 5,
+				Latencies:    &Latencies{AvgMs: 50},
+				Generator:    generator,
+			})
+		}
+	}
+
+	grouped := GroupAggregatedBuckets(buckets)
+	if grouped.DataPoints[0].VirtualUsers != 10 || grouped.DataPoints[0].Requests != 20 {
+		t.Error("Failed to merge generators: ", grouped.DataPoints[0].VirtualUsers, grouped.DataPoints[0].Requests)
+	}
+
+	summary, _ := SummarizeAggregatedBuckets(buckets)
+	if summary.MaxVirtualUsers != 10 || summary.TotalRequests != 1200 {
+		t.Error("Failed to summarize generators: ", summary.MaxVirtualUsers, summary.TotalRequests)
+	}
+}
+
+func TestPeriodTimeAggregationLevel(t *testing.T) {
+	tests := map[time.Duration]TimeAggregationLevel{
+		10 * time.Second:              "10s",
+		2 * time.Minute:               "2m",
+		90 * time.Second:              "1m30s",
+		12 * time.Hour:                "12h",
+		90 * time.Minute:              "1h30m",
+		1500 * time.Millisecond:       "1.5s",
+		12*time.Hour + 30*time.Second: "12h0m30s",
+	}
+
+	for period, expected := range tests {
+		if level := periodTimeAggregationLevel(period); level != expected || level.Duration() != period {
+			t.Error("Failed to name period ", period, ": ", level, " expected: ", expected)
+		}
+	}
+}
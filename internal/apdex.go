@@ -0,0 +1,93 @@
+package internal
+
+import "github.com/montanaflynn/stats"
+
+// ApdexConfig sets the satisfied threshold T in milliseconds, globally or for
+// specific labels. Requests up to T are satisfied, up to 4T tolerating, and
+// slower or failed requests frustrated.
+type ApdexConfig struct {
+	ThresholdMs uint64            `json:"thresholdMs"`
+	Labels      map[string]uint64 `json:"labels"`
+}
+
+func (c ApdexConfig) Enabled() bool {
+	return c.ThresholdMs > 0 || len(c.Labels) > 0
+}
+
+func (c ApdexConfig) Threshold(label string) uint64 {
+	if threshold, ok := c.Labels[label]; ok {
+		return threshold
+	}
+	return c.ThresholdMs
+}
+
+type Apdex struct {
+	Satisfied  uint64  `json:"satisfied"`
+	Tolerating uint64  `json:"tolerating"`
+	Frustrated uint64  `json:"frustrated"`
+	Score      float64 `json:"score"`
+}
+
+// ApplyApdexThresholds sets the threshold of each row from its label. Labels
+// should be normalized first, so thresholds match the published labels.
+func ApplyApdexThresholds(rows []UngroupedMetricDataPoint, config ApdexConfig) {
+	if !config.Enabled() {
+		return
+	}
+
+	for i := range rows {
+		rows[i].ApdexThresholdMs = config.Threshold(rows[i].Label)
+	}
+}
+
+// apdexCounter counts requests of rows with an Apdex threshold.
+type apdexCounter struct {
+	satisfied  uint64
+	tolerating uint64
+	frustrated uint64
+}
+
+func (c *apdexCounter) add(dp UngroupedMetricDataPoint) {
+	if dp.ApdexThresholdMs == 0 || dp.Requests == 0 {
+		return
+	}
+
+	// rows with several requests only report failures, successful requests
+	// are classified by the row latency
+	succeeded := dp.Requests - dp.Failures
+	if dp.Failures > dp.Requests {
+		succeeded = 0
+	}
+	c.frustrated += dp.Requests - succeeded
+
+	switch {
+	case dp.Latency <= dp.ApdexThresholdMs:
+		c.satisfied += succeeded
+	case dp.Latency <= 4*dp.ApdexThresholdMs:
+		c.tolerating += succeeded
+	default:
+		c.frustrated += succeeded
+	}
+}
+
+func (c *apdexCounter) merge(other apdexCounter) {
+	c.satisfied += other.satisfied
+	c.tolerating += other.tolerating
+	c.frustrated += other.frustrated
+}
+
+func (c *apdexCounter) summary() *Apdex {
+	total := c.satisfied + c.tolerating + c.frustrated
+	if total == 0 {
+		return nil
+	}
+
+	apdex := Apdex{
+		Satisfied:  c.satisfied,
+		Tolerating: c.tolerating,
+		Frustrated: c.frustrated,
+	}
+	apdex.Score, _ = stats.Round((float64(c.satisfied)+float64(c.tolerating)/2)/float64(total), 4)
+
+	return &apdex
+}
@@ -0,0 +1,38 @@
+package internal
+
+import "testing"
+
+func TestApdexCounter(t *testing.T) {
+	config := ApdexConfig{ThresholdMs: 100, Labels: map[string]uint64{"slow": 1000}}
+	rows := []UngroupedMetricDataPoint{
+		{Label: "fast", Requests: 1, Latency: 50},
+		{Label: "fast", Requests: 1, Latency: 300},
+		{Label: "fast", Requests: 1, Latency: 500},
+		{Label: "fast", Requests: 1, Failures: 1, Latency: 10},
+		{Label: "slow", Requests: 1, Latency: 900},
+	}
+	ApplyApdexThresholds(rows, config)
+
+	var counter apdexCounter
+	for _, row := range rows {
+		counter.add(row)
+	}
+
+	apdex := counter.summary()
+	if apdex.Satisfied != 2 || apdex.Tolerating != 1 || apdex.Frustrated != 2 {
+		t.Error("Failed to classify requests: ", apdex.Satisfied, apdex.Tolerating, apdex.Frustrated, " expected: ", 2, 1, 2)
+	}
+
+	if apdex.Score != 0.5 {
+		t.Error("Failed to calculate score: ", apdex.Score, " expected: ", 0.5)
+	}
+}
+
+func TestApdexCounterWithoutThreshold(t *testing.T) {
+	var counter apdexCounter
+	counter.add(UngroupedMetricDataPoint{Requests: 1, Latency: 50})
+
+	if counter.summary() != nil {
+		t.Error("Failed to skip rows without a threshold")
+	}
+}
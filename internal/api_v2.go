@@ -6,19 +6,22 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
+	"time"
 
 	"github.com/getsentry/sentry-go"
 	"github.com/pkg/errors"
 )
 
 type CreateTestRunRequestData struct {
-	ApiKey          string `json:"apiKey"`
-	ScenarioName    string `json:"scenarioName"`
-	RunName         string `json:"runName"`
-	Environment     string `json:"environment"`
-	StartedAt       uint64 `json:"startedAt"`
-	StoppedAt       uint64 `json:"stoppedAt"`
-	PublishStrategy string `json:"publishStrategy"`
+	ApiKey               string `json:"apiKey"`
+	ScenarioName         string `json:"scenarioName"`
+	RunName              string `json:"runName"`
+	Environment          string `json:"environment"`
+	StartedAt            uint64 `json:"startedAt"`
+	StoppedAt            uint64 `json:"stoppedAt"`
+	PublishStrategy      string `json:"publishStrategy"`
+	SteadyStateStartedAt uint64 `json:"steadyStateStartedAt,omitempty"`
+	SteadyStateStoppedAt uint64 `json:"steadyStateStoppedAt,omitempty"`
 }
 
 type CreateTestRunRequest struct {
@@ -55,8 +58,11 @@ type LingoSample struct {
 	GrpThreads      int    `json:"grpThreads"`
 	AllThreads      int    `json:"allThreads"`
 	URL             string `json:"url"`
+	Latency         uint64 `json:"latency"`
 	IdleTime        uint64 `json:"idleTime"`
 	Connect         uint64 `json:"connect"`
+	Generator       string `json:"generator,omitempty"`
+	Transaction     bool   `json:"transaction,omitempty"`
 }
 
 type CreateTestSamplesRequestData struct {
@@ -78,63 +84,157 @@ type UpdateTestRunRequest struct {
 }
 
 type NewMetric struct {
-	OperationName  string  `json:"operationName"`
-	RequestCount   uint64  `json:"requestCount"`
-	FailureCount   uint64  `json:"failureCount"`
-	VirtualUserMax uint64  `json:"virtualUserMax"`
-	LatencyAvgMs   float64 `json:"latencyAvgMs"`
-	LatencyMinMs   float64 `json:"latencyMinMs"`
-	LatencyMaxMs   float64 `json:"latencyMaxMs"`
-	LatencyP50Ms   float64 `json:"latencyP50Ms"`
-	LatencyP75Ms   float64 `json:"latencyP75Ms"`
-	LatencyP90Ms   float64 `json:"latencyP90Ms"`
-	LatencyP95Ms   float64 `json:"latencyP95Ms"`
-	LatencyP99Ms   float64 `json:"latencyP99Ms"`
+	OperationName  string   `json:"operationName"`
+	Scope          string   `json:"scope,omitempty"`
+	Transaction    bool     `json:"transaction,omitempty"`
+	RequestCount   uint64   `json:"requestCount"`
+	FailureCount   uint64   `json:"failureCount"`
+	VirtualUserMax uint64   `json:"virtualUserMax"`
+	LatencyAvgMs   float64  `json:"latencyAvgMs"`
+	LatencyMinMs   float64  `json:"latencyMinMs"`
+	LatencyMaxMs   float64  `json:"latencyMaxMs"`
+	LatencyP50Ms   *float64 `json:"latencyP50Ms,omitempty"`
+	LatencyP75Ms   *float64 `json:"latencyP75Ms,omitempty"`
+	LatencyP90Ms   *float64 `json:"latencyP90Ms,omitempty"`
+	LatencyP95Ms   *float64 `json:"latencyP95Ms,omitempty"`
+	LatencyP99Ms   *float64 `json:"latencyP99Ms,omitempty"`
+
+	RequestsPerSecondMean      float64 `json:"requestsPerSecondMean"`
+	RequestsPerSecondPeak      float64 `json:"requestsPerSecondPeak"`
+	BytesReceivedPerSecondMean float64 `json:"bytesReceivedPerSecondMean"`
+	BytesReceivedPerSecondPeak float64 `json:"bytesReceivedPerSecondPeak"`
+	BytesSentPerSecondMean     float64 `json:"bytesSentPerSecondMean"`
+	BytesSentPerSecondPeak     float64 `json:"bytesSentPerSecondPeak"`
+	BytesReceivedTotal         uint64  `json:"bytesReceivedTotal"`
+	BytesSentTotal             uint64  `json:"bytesSentTotal"`
+	ResponseBytesAvg           float64 `json:"responseBytesAvg"`
+
+	NewLatencyBreakdown
+	*NewApdex
+}
+
+// NewApdex is embedded in metrics and omitted unless an Apdex threshold is
+// configured.
+type NewApdex struct {
+	ApdexScore      float64 `json:"apdexScore"`
+	ApdexSatisfied  uint64  `json:"apdexSatisfied"`
+	ApdexTolerating uint64  `json:"apdexTolerating"`
+	ApdexFrustrated uint64  `json:"apdexFrustrated"`
+}
+
+// NewLatencyBreakdown is embedded in metrics, fields are omitted when the
+// format doesn't report request timings.
+type NewLatencyBreakdown struct {
+	ConnectAvgMs float64 `json:"connectAvgMs,omitempty"`
+	ConnectP50Ms float64 `json:"connectP50Ms,omitempty"`
+	ConnectP90Ms float64 `json:"connectP90Ms,omitempty"`
+	ConnectP95Ms float64 `json:"connectP95Ms,omitempty"`
+	ConnectP99Ms float64 `json:"connectP99Ms,omitempty"`
+	ServerAvgMs  float64 `json:"serverAvgMs,omitempty"`
+	ServerP50Ms  float64 `json:"serverP50Ms,omitempty"`
+	ServerP90Ms  float64 `json:"serverP90Ms,omitempty"`
+	ServerP95Ms  float64 `json:"serverP95Ms,omitempty"`
+	ServerP99Ms  float64 `json:"serverP99Ms,omitempty"`
+	IdleAvgMs    float64 `json:"idleAvgMs,omitempty"`
+	IdleP95Ms    float64 `json:"idleP95Ms,omitempty"`
 }
 
 type NewChartMetric struct {
+	Timestamp            uint64   `json:"timestamp"`
+	TimestampMs          uint64   `json:"timestampMs"`
+	TimeAggregationLevel string   `json:"timeAggregationLevel"`
+	OperationName        string   `json:"operationName"`
+	Generator            string   `json:"generator,omitempty"`
+	Transaction          bool     `json:"transaction,omitempty"`
+	RequestCount         uint64   `json:"requestCount"`
+	FailureCount         uint64   `json:"failureCount"`
+	VirtualUserMax       uint64   `json:"virtualUserMax"`
+	LatencyAvgMs         float64  `json:"latencyAvgMs"`
+	LatencyMinMs         float64  `json:"latencyMinMs"`
+	LatencyMaxMs         float64  `json:"latencyMaxMs"`
+	LatencyP50Ms         *float64 `json:"latencyP50Ms,omitempty"`
+	LatencyP75Ms         *float64 `json:"latencyP75Ms,omitempty"`
+	LatencyP90Ms         *float64 `json:"latencyP90Ms,omitempty"`
+	LatencyP95Ms         *float64 `json:"latencyP95Ms,omitempty"`
+	LatencyP99Ms         *float64 `json:"latencyP99Ms,omitempty"`
+
+	RequestsPerSecond      float64 `json:"requestsPerSecond"`
+	BytesReceivedPerSecond float64 `json:"bytesReceivedPerSecond"`
+	BytesSentPerSecond     float64 `json:"bytesSentPerSecond"`
+	ResponseBytesAvg       float64 `json:"responseBytesAvg"`
+
+	NewLatencyBreakdown
+	*NewApdex
+}
+
+type NewCustomMetric struct {
 	Timestamp            uint64  `json:"timestamp"`
+	TimestampMs          uint64  `json:"timestampMs"`
 	TimeAggregationLevel string  `json:"timeAggregationLevel"`
+	MetricName           string  `json:"metricName"`
+	MetricType           string  `json:"metricType"`
 	OperationName        string  `json:"operationName"`
-	RequestCount         uint64  `json:"requestCount"`
-	FailureCount         uint64  `json:"failureCount"`
-	VirtualUserMax       uint64  `json:"virtualUserMax"`
-	LatencyAvgMs         float64 `json:"latencyAvgMs"`
-	LatencyMinMs         float64 `json:"latencyMinMs"`
-	LatencyMaxMs         float64 `json:"latencyMaxMs"`
-	LatencyP50Ms         float64 `json:"latencyP50Ms"`
-	LatencyP75Ms         float64 `json:"latencyP75Ms"`
-	LatencyP90Ms         float64 `json:"latencyP90Ms"`
-	LatencyP95Ms         float64 `json:"latencyP95Ms"`
-	LatencyP99Ms         float64 `json:"latencyP99Ms"`
+	Count                uint64  `json:"count"`
+	Sum                  float64 `json:"sum"`
+	Min                  float64 `json:"min"`
+	Max                  float64 `json:"max"`
+	Avg                  float64 `json:"avg"`
+	Rate                 float64 `json:"rate"`
+	P50                  float64 `json:"p50"`
+	P90                  float64 `json:"p90"`
+	P95                  float64 `json:"p95"`
+	P99                  float64 `json:"p99"`
+}
+
+type NewErrorSummary struct {
+	OperationName string `json:"operationName"`
+	Category      string `json:"category"`
+	Value         string `json:"value"`
+	Count         uint64 `json:"count"`
+}
+
+type NewErrorChartMetric struct {
+	Timestamp            uint64  `json:"timestamp"`
+	TimestampMs          uint64  `json:"timestampMs"`
+	TimeAggregationLevel string  `json:"timeAggregationLevel"`
+	Category             string  `json:"category"`
+	Value                string  `json:"value"`
+	Count                uint64  `json:"count"`
+	Rate                 float64 `json:"rate"`
 }
 
 type TimeAggregationLevel string
 
 const (
 	Undefined     TimeAggregationLevel = ""
+	HalfSecond    TimeAggregationLevel = "500ms"
+	OneSecond     TimeAggregationLevel = "1s"
 	FiveSeconds   TimeAggregationLevel = "5s"
 	ThirtySeconds TimeAggregationLevel = "30s"
 	OneMinute     TimeAggregationLevel = "1m"
 	FiveMinutes   TimeAggregationLevel = "5m"
 	ThirtyMinutes TimeAggregationLevel = "30m"
+	OneHour       TimeAggregationLevel = "1h"
+	SixHours      TimeAggregationLevel = "6h"
 )
 
-func (t TimeAggregationLevel) Seconds() uint64 {
-	switch t {
-	case FiveSeconds:
-		return 5
-	case ThirtySeconds:
-		return 30
-	case OneMinute:
-		return 60
-	case FiveMinutes:
-		return 300
-	case ThirtyMinutes:
-		return 1800
-	default:
-		return 5
+// Duration parses the level as a Go duration, defaulting to 5s.
+func (t TimeAggregationLevel) Duration() time.Duration {
+	duration, err := time.ParseDuration(string(t))
+	if err != nil || duration <= 0 {
+		return 5 * time.Second
 	}
+	return duration
+}
+
+func (t TimeAggregationLevel) Milliseconds() uint64 {
+	return uint64(t.Duration() / time.Millisecond)
+}
+
+// Seconds rounds sub-second levels down, they are only used when time stamps
+// have sub-second resolution.
+func (t TimeAggregationLevel) Seconds() uint64 {
+	return uint64(t.Duration() / time.Second)
 }
 
 type CreateTestChartMetricsRequestData struct {
@@ -146,6 +246,25 @@ type CreateTestChartMetricsRequest struct {
 	Data *CreateTestChartMetricsRequestData `json:"data"`
 }
 
+type CreateTestCustomMetricsRequestData struct {
+	Token   string            `json:"token"`
+	Metrics []NewCustomMetric `json:"metrics"`
+}
+
+type CreateTestCustomMetricsRequest struct {
+	Data *CreateTestCustomMetricsRequestData `json:"data"`
+}
+
+type CreateTestErrorMetricsRequestData struct {
+	Token     string                `json:"token"`
+	Summaries []NewErrorSummary     `json:"summaries"`
+	Metrics   []NewErrorChartMetric `json:"metrics"`
+}
+
+type CreateTestErrorMetricsRequest struct {
+	Data *CreateTestErrorMetricsRequestData `json:"data"`
+}
+
 type CreateTestSummaryMetricsRequestData struct {
 	Token   string      `json:"token"`
 	Metrics []NewMetric `json:"metrics"`
@@ -179,18 +298,12 @@ type GetTestRunResultsResponse struct {
 	} `json:"result"`
 }
 
-func CreateTestRun(host string, apiKey string, name string, startedAt uint64, stoppedAt uint64, publishStrategy string) (*TestRun, error) {
+func CreateTestRun(host string, data CreateTestRunRequestData) (*TestRun, error) {
 	span := sentry.StartSpan(context.Background(), "CreateTestRun")
 	defer span.Finish()
 
 	postBody, err := json.Marshal(CreateTestRunRequest{
-		Data: &CreateTestRunRequestData{
-			ApiKey:          apiKey,
-			ScenarioName:    name,
-			StartedAt:       startedAt,
-			StoppedAt:       stoppedAt,
-			PublishStrategy: publishStrategy,
-		},
+		Data: &data,
 	})
 
 	if err != nil {
@@ -277,6 +390,111 @@ func CreateTestChartMetricsBatch(host string, token string, dataPoints []MetricD
 	return true, nil
 }
 
+func CreateTestCustomMetrics(host string, token string, dataPoints []CustomMetricDataPoint) (bool, error) {
+	span := sentry.StartSpan(context.Background(), "CreateTestCustomMetrics")
+	defer span.Finish()
+
+	batch := 500
+	for i := 0; i < len(dataPoints); i += batch {
+		j := i + batch
+		if j > len(dataPoints) {
+			j = len(dataPoints)
+		}
+
+		if _, err := CreateTestCustomMetricsBatch(host, token, dataPoints[i:j]); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+func CreateTestCustomMetricsBatch(host string, token string, dataPoints []CustomMetricDataPoint) (bool, error) {
+	postBody, err := json.Marshal(CreateTestCustomMetricsRequest{
+		Data: &CreateTestCustomMetricsRequestData{
+			Token:   token,
+			Metrics: mapCustomMetricDataPoints(dataPoints),
+		},
+	})
+
+	if err != nil {
+		return false, errors.Wrap(err, "[test.createCustomMetrics] failed to build request body")
+	}
+
+	resp, err := http.Post(host+"/v2/test.createCustomMetrics", "application/json", bytes.NewBuffer(postBody))
+	if err != nil {
+		return false, errors.Wrap(err, "[test.createCustomMetrics] request failed")
+	}
+
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll((resp.Body))
+	if err != nil {
+		return false, errors.Wrap(err, "[test.createCustomMetrics] failed to parse response")
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return false, errors.Errorf("[test.createCustomMetrics] request failed: %s", string(body))
+	}
+
+	return true, nil
+}
+
+func CreateTestErrorMetrics(host string, token string, breakdown ErrorBreakdown) (bool, error) {
+	span := sentry.StartSpan(context.Background(), "CreateTestErrorMetrics")
+	defer span.Finish()
+
+	summaries := mapErrorSummaries(breakdown.Summaries)
+
+	// summaries are sent with the first batch of chart metrics
+	batch := 500
+	for i := 0; i == 0 || i < len(breakdown.DataPoints); i += batch {
+		j := i + batch
+		if j > len(breakdown.DataPoints) {
+			j = len(breakdown.DataPoints)
+		}
+
+		if _, err := CreateTestErrorMetricsBatch(host, token, summaries, breakdown.DataPoints[i:j]); err != nil {
+			return false, err
+		}
+		summaries = nil
+	}
+
+	return true, nil
+}
+
+func CreateTestErrorMetricsBatch(host string, token string, summaries []NewErrorSummary, dataPoints []ErrorDataPoint) (bool, error) {
+	postBody, err := json.Marshal(CreateTestErrorMetricsRequest{
+		Data: &CreateTestErrorMetricsRequestData{
+			Token:     token,
+			Summaries: summaries,
+			Metrics:   mapErrorDataPoints(dataPoints),
+		},
+	})
+
+	if err != nil {
+		return false, errors.Wrap(err, "[test.createErrorMetrics] failed to build request body")
+	}
+
+	resp, err := http.Post(host+"/v2/test.createErrorMetrics", "application/json", bytes.NewBuffer(postBody))
+	if err != nil {
+		return false, errors.Wrap(err, "[test.createErrorMetrics] request failed")
+	}
+
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll((resp.Body))
+	if err != nil {
+		return false, errors.Wrap(err, "[test.createErrorMetrics] failed to parse response")
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return false, errors.Errorf("[test.createErrorMetrics] request failed: %s", string(body))
+	}
+
+	return true, nil
+}
+
 func CreateTestSummaryMetrics(host string, token string, metrics MetricSummary, metricsByLabel map[string]MetricSummary) (bool, error) {
 	span := sentry.StartSpan(context.Background(), "CreateTestSummaryMetrics")
 	defer span.Finish()
@@ -444,37 +662,165 @@ func mapMetricDataPoints(dataPoints []MetricDataPoint) []NewChartMetric {
 }
 
 func mapMetricDataPoint(dp MetricDataPoint) NewChartMetric {
-	return NewChartMetric{
-		Timestamp:            dp.TimeStamp,
+	metric := NewChartMetric{
+		Timestamp:            dp.TimeStamp / 1000,
+		TimestampMs:          dp.TimeStamp,
 		TimeAggregationLevel: string(dp.TimeAggregationLevel),
 		OperationName:        dp.Label,
+		Generator:            dp.Generator,
+		Transaction:          dp.Transaction,
 		RequestCount:         dp.Requests,
 		FailureCount:         dp.Failures,
 		VirtualUserMax:       dp.VirtualUsers,
 		LatencyAvgMs:         dp.Latencies.AvgMs,
 		LatencyMinMs:         dp.Latencies.MinMs,
 		LatencyMaxMs:         dp.Latencies.MaxMs,
-		LatencyP50Ms:         dp.Latencies.P50Ms,
-		LatencyP75Ms:         dp.Latencies.P75Ms,
-		LatencyP90Ms:         dp.Latencies.P90Ms,
-		LatencyP95Ms:         dp.Latencies.P95Ms,
-		LatencyP99Ms:         dp.Latencies.P99Ms,
 	}
+	metric.LatencyP50Ms, metric.LatencyP75Ms, metric.LatencyP90Ms, metric.LatencyP95Ms, metric.LatencyP99Ms = mapPercentiles(dp.Latencies)
+
+	if dp.Throughput != nil {
+		metric.RequestsPerSecond = dp.Throughput.RequestsPerSecond
+		metric.BytesReceivedPerSecond = dp.Throughput.BytesReceivedPerSecond
+		metric.BytesSentPerSecond = dp.Throughput.BytesSentPerSecond
+		metric.ResponseBytesAvg = dp.Throughput.AvgResponseBytes
+	}
+	metric.NewLatencyBreakdown = mapLatencyBreakdown(dp.LatencyBreakdown)
+	metric.NewApdex = mapApdex(dp.Apdex)
+
+	return metric
 }
 
 func mapMetricSummary(summary MetricSummary) NewMetric {
-	return NewMetric{
+	metric := NewMetric{
 		OperationName:  summary.Label,
+		Scope:          summary.Scope,
+		Transaction:    summary.Transaction,
 		RequestCount:   summary.TotalRequests,
 		FailureCount:   summary.TotalFailures,
 		VirtualUserMax: summary.MaxVirtualUsers,
 		LatencyAvgMs:   summary.Latencies.AvgMs,
 		LatencyMinMs:   summary.Latencies.MinMs,
 		LatencyMaxMs:   summary.Latencies.MaxMs,
-		LatencyP50Ms:   summary.Latencies.P50Ms,
-		LatencyP75Ms:   summary.Latencies.P75Ms,
-		LatencyP90Ms:   summary.Latencies.P90Ms,
-		LatencyP95Ms:   summary.Latencies.P95Ms,
-		LatencyP99Ms:   summary.Latencies.P99Ms,
 	}
+	metric.LatencyP50Ms, metric.LatencyP75Ms, metric.LatencyP90Ms, metric.LatencyP95Ms, metric.LatencyP99Ms = mapPercentiles(summary.Latencies)
+
+	if summary.Throughput != nil {
+		metric.RequestsPerSecondMean = summary.Throughput.MeanRequestsPerSecond
+		metric.RequestsPerSecondPeak = summary.Throughput.PeakRequestsPerSecond
+		metric.BytesReceivedPerSecondMean = summary.Throughput.MeanBytesReceivedPerSecond
+		metric.BytesReceivedPerSecondPeak = summary.Throughput.PeakBytesReceivedPerSecond
+		metric.BytesSentPerSecondMean = summary.Throughput.MeanBytesSentPerSecond
+		metric.BytesSentPerSecondPeak = summary.Throughput.PeakBytesSentPerSecond
+		metric.BytesReceivedTotal = summary.Throughput.TotalBytesReceived
+		metric.BytesSentTotal = summary.Throughput.TotalBytesSent
+		metric.ResponseBytesAvg = summary.Throughput.AvgResponseBytes
+	}
+	metric.NewLatencyBreakdown = mapLatencyBreakdown(summary.LatencyBreakdown)
+	metric.NewApdex = mapApdex(summary.Apdex)
+
+	return metric
+}
+
+// mapPercentiles leaves out percentiles the tool didn't report.
+func mapPercentiles(latencies *Latencies) (*float64, *float64, *float64, *float64, *float64) {
+	percentile := func(percentile Percentiles, value float64) *float64 {
+		if latencies.Unreported&percentile != 0 {
+			return nil
+		}
+		return &value
+	}
+
+	return percentile(Percentile50, latencies.P50Ms),
+		percentile(Percentile75, latencies.P75Ms),
+		percentile(Percentile90, latencies.P90Ms),
+		percentile(Percentile95, latencies.P95Ms),
+		percentile(Percentile99, latencies.P99Ms)
+}
+
+func mapLatencyBreakdown(breakdown *LatencyBreakdown) NewLatencyBreakdown {
+	if breakdown == nil {
+		return NewLatencyBreakdown{}
+	}
+
+	return NewLatencyBreakdown{
+		ConnectAvgMs: breakdown.Connect.AvgMs,
+		ConnectP50Ms: breakdown.Connect.P50Ms,
+		ConnectP90Ms: breakdown.Connect.P90Ms,
+		ConnectP95Ms: breakdown.Connect.P95Ms,
+		ConnectP99Ms: breakdown.Connect.P99Ms,
+		ServerAvgMs:  breakdown.Server.AvgMs,
+		ServerP50Ms:  breakdown.Server.P50Ms,
+		ServerP90Ms:  breakdown.Server.P90Ms,
+		ServerP95Ms:  breakdown.Server.P95Ms,
+		ServerP99Ms:  breakdown.Server.P99Ms,
+		IdleAvgMs:    breakdown.Idle.AvgMs,
+		IdleP95Ms:    breakdown.Idle.P95Ms,
+	}
+}
+
+func mapApdex(apdex *Apdex) *NewApdex {
+	if apdex == nil {
+		return nil
+	}
+
+	return &NewApdex{
+		ApdexScore:      apdex.Score,
+		ApdexSatisfied:  apdex.Satisfied,
+		ApdexTolerating: apdex.Tolerating,
+		ApdexFrustrated: apdex.Frustrated,
+	}
+}
+
+func mapCustomMetricDataPoints(dataPoints []CustomMetricDataPoint) []NewCustomMetric {
+	result := make([]NewCustomMetric, len(dataPoints))
+	for i, dp := range dataPoints {
+		result[i] = NewCustomMetric{
+			Timestamp:            dp.TimeStamp / 1000,
+			TimestampMs:          dp.TimeStamp,
+			TimeAggregationLevel: string(dp.TimeAggregationLevel),
+			MetricName:           dp.Metric,
+			MetricType:           dp.Type,
+			OperationName:        dp.Label,
+			Count:                dp.Count,
+			Sum:                  dp.Sum,
+			Min:                  dp.Min,
+			Max:                  dp.Max,
+			Avg:                  dp.Avg,
+			Rate:                 dp.Rate,
+			P50:                  dp.P50,
+			P90:                  dp.P90,
+			P95:                  dp.P95,
+			P99:                  dp.P99,
+		}
+	}
+	return result
+}
+
+func mapErrorSummaries(summaries []ErrorSummary) []NewErrorSummary {
+	result := make([]NewErrorSummary, len(summaries))
+	for i, summary := range summaries {
+		result[i] = NewErrorSummary{
+			OperationName: summary.Label,
+			Category:      summary.Category,
+			Value:         summary.Value,
+			Count:         summary.Count,
+		}
+	}
+	return result
+}
+
+func mapErrorDataPoints(dataPoints []ErrorDataPoint) []NewErrorChartMetric {
+	result := make([]NewErrorChartMetric, len(dataPoints))
+	for i, dp := range dataPoints {
+		result[i] = NewErrorChartMetric{
+			Timestamp:            dp.TimeStamp / 1000,
+			TimestampMs:          dp.TimeStamp,
+			TimeAggregationLevel: string(dp.TimeAggregationLevel),
+			Category:             dp.Category,
+			Value:                dp.Value,
+			Count:                dp.Count,
+			Rate:                 dp.Rate,
+		}
+	}
+	return result
 }
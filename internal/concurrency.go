@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// VirtualUserSeries holds virtual user counts over time, as reported by the
+// load test tool. Time stamps are in milliseconds.
+type VirtualUserSeries struct {
+	timeStamps []uint64
+	values     []uint64
+}
+
+func (s *VirtualUserSeries) Add(timeStamp uint64, value uint64) {
+	s.timeStamps = append(s.timeStamps, timeStamp)
+	s.values = append(s.values, value)
+}
+
+func (s *VirtualUserSeries) Len() int {
+	return len(s.timeStamps)
+}
+
+// At returns the last reported count at or before the time stamp.
+func (s *VirtualUserSeries) At(timeStamp uint64) uint64 {
+	index := sort.Search(len(s.timeStamps), func(i int) bool {
+		return s.timeStamps[i] > timeStamp
+	})
+
+	if index == 0 {
+		return 0
+	}
+	return s.values[index-1]
+}
+
+func (s *VirtualUserSeries) sort() {
+	sort.Sort(s)
+}
+
+func (s *VirtualUserSeries) Less(i int, j int) bool {
+	return s.timeStamps[i] < s.timeStamps[j]
+}
+
+func (s *VirtualUserSeries) Swap(i int, j int) {
+	s.timeStamps[i], s.timeStamps[j] = s.timeStamps[j], s.timeStamps[i]
+	s.values[i], s.values[j] = s.values[j], s.values[i]
+}
+
+type UserEvent struct {
+	TimeStamp uint64
+	Started   bool
+}
+
+// VirtualUserSeriesFromEvents replays user start and end events into a
+// running count of active users.
+func VirtualUserSeriesFromEvents(events []UserEvent) *VirtualUserSeries {
+	sort.SliceStable(events, func(i int, j int) bool {
+		return events[i].TimeStamp < events[j].TimeStamp
+	})
+
+	series := &VirtualUserSeries{}
+	var active int64
+	for _, event := range events {
+		if event.Started {
+			active++
+		} else if active > 0 {
+			active--
+		}
+		series.Add(event.TimeStamp, uint64(active))
+	}
+
+	return series
+}
+
+// applyVirtualUsers sets the virtual users of each row from the series, or
+// estimates them from overlapping requests when the series is empty. starts
+// holds the millisecond start time of each row.
+func applyVirtualUsers(rows []UngroupedMetricDataPoint, starts []uint64, series *VirtualUserSeries) {
+	if series.Len() > 0 {
+		series.sort()
+		for i := range rows {
+			rows[i].VirtualUsers = series.At(starts[i])
+		}
+		return
+	}
+
+	latencies := make([]uint64, len(rows))
+	for i, row := range rows {
+		latencies[i] = row.Latency
+	}
+
+	for i, inFlight := range estimateConcurrency(starts, latencies) {
+		rows[i].VirtualUsers = inFlight
+	}
+}
+
+type endTimeHeap []uint64
+
+func (h endTimeHeap) Len() int            { return len(h) }
+func (h endTimeHeap) Less(i, j int) bool  { return h[i] < h[j] }
+func (h endTimeHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *endTimeHeap) Push(x interface{}) { *h = append(*h, x.(uint64)) }
+func (h *endTimeHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// estimateConcurrency counts the requests in flight when each request starts.
+// Without think time between requests, this approximates the number of
+// virtual users.
+func estimateConcurrency(starts []uint64, latencies []uint64) []uint64 {
+	order := make([]int, len(starts))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i int, j int) bool {
+		return starts[order[i]] < starts[order[j]]
+	})
+
+	inFlight := make([]uint64, len(starts))
+	ends := &endTimeHeap{}
+	for _, i := range order {
+		for ends.Len() > 0 && (*ends)[0] <= starts[i] {
+			heap.Pop(ends)
+		}
+
+		heap.Push(ends, starts[i]+latencies[i])
+		inFlight[i] = uint64(ends.Len())
+	}
+
+	return inFlight
+}
@@ -0,0 +1,52 @@
+package internal
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+// Config holds the optional settings that can be provided with --config.
+//
+// Example:
+//
+//	{
+//	  "labels": {
+//	    "rewrites": [{"pattern": "\\?.*$", "replacement": ""}],
+//	    "templatePaths": true,
+//	    "aliases": {"GET /health": "health check"},
+//	    "maxLabels": 100
+//	  },
+//	  "filters": {
+//	    "excludeLabels": ["^GET /health$"],
+//	    "skipFirst": "2m"
+//	  },
+//	  "apdex": {
+//	    "thresholdMs": 500,
+//	    "labels": {"checkout": 1000}
+//	  }
+//	}
+type Config struct {
+	Labels  LabelRules   `json:"labels"`
+	Filters FilterConfig `json:"filters"`
+	Apdex   ApdexConfig  `json:"apdex"`
+}
+
+func LoadConfig(file string) (*Config, error) {
+	var config Config
+	if file == "" {
+		return &config, nil
+	}
+
+	contents, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read config file %s", file)
+	}
+
+	if err := json.Unmarshal(contents, &config); err != nil {
+		return nil, errors.Wrapf(err, "cannot parse config file %s", file)
+	}
+
+	return &config, nil
+}
@@ -0,0 +1,131 @@
+package internal
+
+import (
+	"sort"
+
+	"github.com/montanaflynn/stats"
+)
+
+const (
+	CustomMetricCounter = "counter"
+	CustomMetricGauge   = "gauge"
+	CustomMetricRate    = "rate"
+	CustomMetricTrend   = "trend"
+)
+
+// CustomMetricPoint is a single value of a metric other than request latency,
+// eg. a k6 timing breakdown or a user defined counter.
+type CustomMetricPoint struct {
+	Metric    string
+	Type      string
+	Label     string
+	TimeStamp uint64
+	Value     float64
+}
+
+type CustomMetricDataPoint struct {
+	Metric               string
+	Type                 string
+	Label                string
+	TimeStamp            uint64
+	TimeAggregationLevel TimeAggregationLevel
+	Count                uint64
+	Sum                  float64
+	Min                  float64
+	Max                  float64
+	Avg                  float64
+	// Rate is the share of non-zero values, only set for rate metrics.
+	Rate float64
+	// Percentiles are only set for trend metrics.
+	P50 float64
+	P90 float64
+	P95 float64
+	P99 float64
+}
+
+type customMetricKey struct {
+	metric    string
+	label     string
+	timeStamp uint64
+}
+
+// GroupCustomMetrics aggregates points per metric, label and time bucket for
+// every time aggregation level. Points are also aggregated without a label.
+func GroupCustomMetrics(points []CustomMetricPoint) []CustomMetricDataPoint {
+	var grouped []CustomMetricDataPoint
+
+	for _, timeAggregationLevel := range allTimeAggregationLevels {
+		buckets := make(map[customMetricKey][]CustomMetricPoint)
+		for _, point := range points {
+			timeStamp := calculateIntervalFloor(point.TimeStamp, timeAggregationLevel.Milliseconds())
+			labeled := customMetricKey{point.Metric, point.Label, timeStamp}
+			buckets[labeled] = append(buckets[labeled], point)
+			if point.Label != "" {
+				overall := customMetricKey{point.Metric, "", timeStamp}
+				buckets[overall] = append(buckets[overall], point)
+			}
+		}
+
+		for key, bucket := range buckets {
+			grouped = append(grouped, groupCustomMetricBatch(bucket, key, timeAggregationLevel))
+		}
+	}
+
+	sort.SliceStable(grouped, func(i int, j int) bool {
+		return grouped[i].TimeStamp < grouped[j].TimeStamp
+	})
+
+	return grouped
+}
+
+func groupCustomMetricBatch(points []CustomMetricPoint, key customMetricKey, timeAggregationLevel TimeAggregationLevel) CustomMetricDataPoint {
+	grouped := CustomMetricDataPoint{
+		Metric:               key.metric,
+		Type:                 points[0].Type,
+		Label:                key.label,
+		TimeStamp:            key.timeStamp,
+		TimeAggregationLevel: timeAggregationLevel,
+		Count:                uint64(len(points)),
+	}
+
+	values := make([]float64, len(points))
+	var nonZero int
+	for i, point := range points {
+		values[i] = point.Value
+		if point.Value != 0 {
+			nonZero++
+		}
+	}
+
+	grouped.Sum, _ = stats.Sum(values)
+	grouped.Min, _ = stats.Min(values)
+	grouped.Max, _ = stats.Max(values)
+	grouped.Avg, _ = stats.Mean(values)
+	grouped.Avg, _ = stats.Round(grouped.Avg, 2)
+
+	switch grouped.Type {
+	case CustomMetricRate:
+		grouped.Rate, _ = stats.Round(float64(nonZero)/float64(len(points)), 4)
+	case CustomMetricTrend:
+		latencies := calculateLatencySummary(values)
+		grouped.P50 = latencies.P50Ms
+		grouped.P90 = latencies.P90Ms
+		grouped.P95 = latencies.P95Ms
+		grouped.P99 = latencies.P99Ms
+	}
+
+	return grouped
+}
+
+// TrimCustomMetrics drops points outside of the published run, eg. after
+// filters removed a warm-up period.
+func TrimCustomMetrics(points []CustomMetricPoint, startedAt uint64, stoppedAt uint64) []CustomMetricPoint {
+	trimmed := make([]CustomMetricPoint, 0, len(points))
+	for _, point := range points {
+		if point.TimeStamp >= startedAt && point.TimeStamp <= stoppedAt {
+			trimmed = append(trimmed, point)
+		}
+	}
+
+	return trimmed
+}
@@ -0,0 +1,26 @@
+package internal
+
+import "fmt"
+
+const (
+	EnvironmentProduction  = "production"
+	EnvironmentDevelopment = "development"
+)
+
+func APIHost(environment string) (string, error) {
+	switch environment {
+	case EnvironmentProduction:
+		return "https://latency-lingo.web.app", nil
+	case EnvironmentDevelopment:
+		return "http://localhost:5000", nil
+	default:
+		return "", fmt.Errorf("unknown environment %s", environment)
+	}
+}
+
+func ReportURL(environment string, runId string) string {
+	if environment == EnvironmentDevelopment {
+		return "http://localhost:3000/test-runs/" + runId
+	}
+	return "https://latencylingo.com/test-runs/" + runId
+}
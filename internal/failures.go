@@ -0,0 +1,218 @@
+package internal
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/montanaflynn/stats"
+)
+
+const (
+	ErrorCategoryStatus    = "status"
+	ErrorCategoryMessage   = "message"
+	ErrorCategoryAssertion = "assertion"
+
+	maxFailureMessageLength = 200
+	// error values beyond this count are folded into "other" in time series
+	maxErrorSeriesValues = 10
+)
+
+var (
+	uuidPattern       = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+	urlPattern        = regexp.MustCompile(`https?://\S+`)
+	numberPattern     = regexp.MustCompile(`\d+`)
+	whitespacePattern = regexp.MustCompile(`\s+`)
+)
+
+// ErrorEvent is a single failure, classified by status, message or assertion.
+type ErrorEvent struct {
+	TimeStamp uint64
+	Label     string
+	Category  string
+	Value     string
+}
+
+type ErrorSummary struct {
+	Label    string
+	Category string
+	Value    string
+	Count    uint64
+}
+
+type ErrorDataPoint struct {
+	Category             string
+	Value                string
+	TimeStamp            uint64
+	TimeAggregationLevel TimeAggregationLevel
+	Count                uint64
+	// Rate is the share of requests in the bucket that failed with this error.
+	Rate float64
+}
+
+type ErrorBreakdown struct {
+	Summaries  []ErrorSummary
+	DataPoints []ErrorDataPoint
+}
+
+// NormalizeFailureMessage strips values that vary between requests, eg. ids
+// and urls, so messages caused by the same problem are counted together.
+func NormalizeFailureMessage(message string) string {
+	message = strings.TrimSpace(message)
+	if index := strings.IndexAny(message, "\r\n"); index != -1 {
+		message = message[:index]
+	}
+
+	message = urlPattern.ReplaceAllString(message, "{url}")
+	message = uuidPattern.ReplaceAllString(message, "{id}")
+	message = numberPattern.ReplaceAllString(message, "{n}")
+	message = whitespacePattern.ReplaceAllString(message, " ")
+
+	if len(message) > maxFailureMessageLength {
+		message = message[:maxFailureMessageLength]
+	}
+
+	return message
+}
+
+func ErrorEventsFromRows(rows []UngroupedMetricDataPoint) []ErrorEvent {
+	var events []ErrorEvent
+	for _, row := range rows {
+		// transactions repeat the failures of their requests
+		if row.Failures == 0 || row.Transaction {
+			continue
+		}
+
+		if row.ResponseCode != "" {
+			events = append(events, ErrorEvent{row.TimeStamp, row.Label, ErrorCategoryStatus, row.ResponseCode})
+		}
+		if row.FailureMessage != "" {
+			events = append(events, ErrorEvent{row.TimeStamp, row.Label, ErrorCategoryMessage, NormalizeFailureMessage(row.FailureMessage)})
+		}
+		if row.AssertionName != "" {
+			events = append(events, ErrorEvent{row.TimeStamp, row.Label, ErrorCategoryAssertion, row.AssertionName})
+		}
+	}
+
+	return events
+}
+
+type errorKey struct {
+	label    string
+	category string
+	value    string
+}
+
+// CalculateErrorBreakdown counts errors overall and by label, and builds
+// error rate time series by category for every time aggregation level.
+func CalculateErrorBreakdown(rows []UngroupedMetricDataPoint, events []ErrorEvent) ErrorBreakdown {
+	counts := make(map[errorKey]uint64)
+	for _, event := range events {
+		counts[errorKey{"", event.Category, event.Value}]++
+		if event.Label != "" {
+			counts[errorKey{event.Label, event.Category, event.Value}]++
+		}
+	}
+
+	var breakdown ErrorBreakdown
+	for key, count := range counts {
+		breakdown.Summaries = append(breakdown.Summaries, ErrorSummary{key.label, key.category, key.value, count})
+	}
+
+	sort.SliceStable(breakdown.Summaries, func(i int, j int) bool {
+		a, b := breakdown.Summaries[i], breakdown.Summaries[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.Label != b.Label {
+			return a.Label < b.Label
+		}
+		if a.Category != b.Category {
+			return a.Category < b.Category
+		}
+		return a.Value < b.Value
+	})
+
+	seriesValues := topErrorValues(breakdown.Summaries)
+	for _, timeAggregationLevel := range allTimeAggregationLevels {
+		breakdown.DataPoints = append(breakdown.DataPoints, groupErrorEvents(rows, events, seriesValues, timeAggregationLevel)...)
+	}
+
+	return breakdown
+}
+
+// topErrorValues picks the most frequent values of each category overall.
+func topErrorValues(summaries []ErrorSummary) map[errorKey]bool {
+	top := make(map[errorKey]bool)
+	perCategory := make(map[string]int)
+	for _, summary := range summaries {
+		if summary.Label != "" || perCategory[summary.Category] >= maxErrorSeriesValues {
+			continue
+		}
+
+		top[errorKey{"", summary.Category, summary.Value}] = true
+		perCategory[summary.Category]++
+	}
+
+	return top
+}
+
+func groupErrorEvents(rows []UngroupedMetricDataPoint, events []ErrorEvent, seriesValues map[errorKey]bool, timeAggregationLevel TimeAggregationLevel) []ErrorDataPoint {
+	requests := make(map[uint64]uint64)
+	for _, row := range rows {
+		if !row.Transaction {
+			requests[calculateIntervalFloor(row.TimeStamp, timeAggregationLevel.Milliseconds())] += row.Requests
+		}
+	}
+
+	type bucketKey struct {
+		timeStamp uint64
+		category  string
+		value     string
+	}
+
+	counts := make(map[bucketKey]uint64)
+	for _, event := range events {
+		value := event.Value
+		if !seriesValues[errorKey{"", event.Category, value}] {
+			value = OtherLabel
+		}
+
+		counts[bucketKey{calculateIntervalFloor(event.TimeStamp, timeAggregationLevel.Milliseconds()), event.Category, value}]++
+	}
+
+	var dataPoints []ErrorDataPoint
+	for key, count := range counts {
+		dataPoint := ErrorDataPoint{
+			Category:             key.category,
+			Value:                key.value,
+			TimeStamp:            key.timeStamp,
+			TimeAggregationLevel: timeAggregationLevel,
+			Count:                count,
+		}
+
+		if requests[key.timeStamp] > 0 {
+			dataPoint.Rate, _ = stats.Round(float64(count)/float64(requests[key.timeStamp]), 4)
+		}
+		dataPoints = append(dataPoints, dataPoint)
+	}
+
+	sort.SliceStable(dataPoints, func(i int, j int) bool {
+		return dataPoints[i].TimeStamp < dataPoints[j].TimeStamp
+	})
+
+	return dataPoints
+}
+
+// TrimErrorEvents drops events outside of the published run, eg. after
+// filters removed a warm-up period.
+func TrimErrorEvents(events []ErrorEvent, startedAt uint64, stoppedAt uint64) []ErrorEvent {
+	trimmed := make([]ErrorEvent, 0, len(events))
+	for _, event := range events {
+		if event.TimeStamp >= startedAt && event.TimeStamp <= stoppedAt {
+			trimmed = append(trimmed, event)
+		}
+	}
+
+	return trimmed
+}
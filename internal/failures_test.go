@@ -0,0 +1,42 @@
+package internal
+
+import "testing"
+
+func TestNormalizeFailureMessage(t *testing.T) {
+	cases := map[string]string{
+		"Non HTTP response code: java.net.SocketTimeoutException":                  "Non HTTP response code: java.net.SocketTimeoutException",
+		"order 0b7e9c5a-3f4d-4b8e-9a3c-1d2e3f4a5b6c not found":                     "order {id} not found",
+		"request to https://example.com/orders/123 timed out after 30000ms\ntrace": "request to {url} timed out after {n}ms",
+	}
+
+	for message, expected := range cases {
+		if result := NormalizeFailureMessage(message); result != expected {
+			t.Error("Failed to normalize failure message: ", message, " result: ", result, " expected: ", expected)
+		}
+	}
+}
+
+func TestCalculateErrorBreakdown(t *testing.T) {
+	rows := []UngroupedMetricDataPoint{
+		{TimeStamp: 1000000, Label: "a", Requests: 1, Failures: 1, ResponseCode: "500"},
+		{TimeStamp: 1001000, Label: "a", Requests: 1, Failures: 1, ResponseCode: "500"},
+		{TimeStamp: 1002000, Label: "b", Requests: 1, Failures: 1, ResponseCode: "404"},
+		{TimeStamp: 1003000, Label: "b", Requests: 1},
+	}
+
+	breakdown := CalculateErrorBreakdown(rows, ErrorEventsFromRows(rows))
+	if len(breakdown.Summaries) != 4 {
+		t.Fatal("Failed to count errors: ", len(breakdown.Summaries), " expected: ", 4)
+	}
+
+	top := breakdown.Summaries[0]
+	if top.Label != "" || top.Value != "500" || top.Count != 2 {
+		t.Error("Failed to sort errors by count: ", top)
+	}
+
+	for _, dp := range breakdown.DataPoints {
+		if dp.TimeAggregationLevel == FiveSeconds && dp.Value == "500" && dp.Rate != 0.5 {
+			t.Error("Failed to calculate error rate: ", dp.Rate, " expected: ", 0.5)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package internal
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// FilterConfig describes which rows and samples to drop before publishing.
+// Label patterns are matched against the raw labels, before normalization.
+type FilterConfig struct {
+	IncludeLabels []string `json:"includeLabels"`
+	ExcludeLabels []string `json:"excludeLabels"`
+	// From and To accept an absolute timestamp or an offset from the start of
+	// the run, eg. "2m".
+	From      string `json:"from"`
+	To        string `json:"to"`
+	SkipFirst string `json:"skipFirst"`
+	// JMeter only
+	ExcludeDataTypes     []string `json:"excludeDataTypes"`
+	ExcludeResponseCodes []string `json:"excludeResponseCodes"`
+	ExcludeThreadNames   []string `json:"excludeThreadNames"`
+}
+
+func (c FilterConfig) HasLabelFilters() bool {
+	return len(c.IncludeLabels) > 0 || len(c.ExcludeLabels) > 0
+}
+
+func (c FilterConfig) HasTimeBounds() bool {
+	return c.From != "" || c.To != "" || c.SkipFirst != ""
+}
+
+// HasSampleFilters reports whether JMeter only filters are set.
+func (c FilterConfig) HasSampleFilters() bool {
+	return len(c.ExcludeDataTypes) > 0 || len(c.ExcludeResponseCodes) > 0 || len(c.ExcludeThreadNames) > 0
+}
+
+type timeBound struct {
+	set    bool
+	offset time.Duration
+	millis uint64
+}
+
+type Filter struct {
+	includeLabels        []*regexp.Regexp
+	excludeLabels        []*regexp.Regexp
+	excludeThreadNames   []*regexp.Regexp
+	excludeDataTypes     map[string]bool
+	excludeResponseCodes map[string]bool
+	from                 timeBound
+	to                   timeBound
+	skipFirst            time.Duration
+}
+
+func NewFilter(config FilterConfig) (*Filter, error) {
+	var (
+		filter Filter
+		err    error
+	)
+
+	if filter.includeLabels, err = compilePatterns(config.IncludeLabels); err != nil {
+		return nil, err
+	}
+	if filter.excludeLabels, err = compilePatterns(config.ExcludeLabels); err != nil {
+		return nil, err
+	}
+	if filter.excludeThreadNames, err = compilePatterns(config.ExcludeThreadNames); err != nil {
+		return nil, err
+	}
+	if filter.from, err = parseTimeBound(config.From); err != nil {
+		return nil, err
+	}
+	if filter.to, err = parseTimeBound(config.To); err != nil {
+		return nil, err
+	}
+
+	if config.SkipFirst != "" {
+		filter.skipFirst, err = time.ParseDuration(config.SkipFirst)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid warm-up duration %s", config.SkipFirst)
+		}
+	}
+
+	filter.excludeDataTypes = make(map[string]bool)
+	for _, dataType := range config.ExcludeDataTypes {
+		filter.excludeDataTypes[dataType] = true
+	}
+
+	filter.excludeResponseCodes = make(map[string]bool)
+	for _, responseCode := range config.ExcludeResponseCodes {
+		filter.excludeResponseCodes[responseCode] = true
+	}
+
+	return &filter, nil
+}
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var compiled []*regexp.Regexp
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid filter pattern %s", pattern)
+		}
+		compiled = append(compiled, re)
+	}
+
+	return compiled, nil
+}
+
+func parseTimeBound(value string) (timeBound, error) {
+	if value == "" {
+		return timeBound{}, nil
+	}
+
+	if offset, err := time.ParseDuration(value); err == nil {
+		return timeBound{set: true, offset: offset}, nil
+	}
+
+	if millis, ok := tryParseTimeStampMillis(value); ok {
+		return timeBound{set: true, millis: millis}, nil
+	}
+
+	return timeBound{}, errors.Errorf("invalid time bound %s, expected a timestamp or an offset like 2m", value)
+}
+
+func (b timeBound) resolve(startMillis uint64) uint64 {
+	if b.millis != 0 {
+		return b.millis
+	}
+	return startMillis + uint64(b.offset.Milliseconds())
+}
+
+// window returns the inclusive millisecond range of rows to keep for a run
+// starting at startMillis.
+func (f *Filter) window(startMillis uint64) (uint64, uint64) {
+	from := startMillis + uint64(f.skipFirst.Milliseconds())
+	if f.from.set && f.from.resolve(startMillis) > from {
+		from = f.from.resolve(startMillis)
+	}
+
+	var to uint64 = math.MaxUint64
+	if f.to.set {
+		to = f.to.resolve(startMillis)
+	}
+
+	return from, to
+}
+
+func (f *Filter) keep(label string, dataType string, responseCode string, threadName string) bool {
+	if !f.keepLabel(label) || matchesAny(f.excludeThreadNames, threadName) {
+		return false
+	}
+
+	return !f.excludeDataTypes[dataType] && !f.excludeResponseCodes[responseCode]
+}
+
+func (f *Filter) keepLabel(label string) bool {
+	if len(f.includeLabels) > 0 && !matchesAny(f.includeLabels, label) {
+		return false
+	}
+
+	return !matchesAny(f.excludeLabels, label)
+}
+
+func matchesAny(patterns []*regexp.Regexp, value string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterDataPoints expects rows sorted by time stamp.
+func FilterDataPoints(rows []UngroupedMetricDataPoint, filter *Filter) []UngroupedMetricDataPoint {
+	if len(rows) == 0 {
+		return rows
+	}
+
+	from, to := filter.window(rows[0].TimeStamp)
+	filtered := make([]UngroupedMetricDataPoint, 0, len(rows))
+	for _, row := range rows {
+		if row.TimeStamp < from || row.TimeStamp > to {
+			continue
+		}
+
+		if filter.keep(row.Label, row.DataType, row.ResponseCode, row.ThreadName) {
+			filtered = append(filtered, row)
+		}
+	}
+
+	return filtered
+}
+
+// FilterSamples expects samples sorted by time stamp.
+func FilterSamples(samples []LingoSample, filter *Filter) []LingoSample {
+	if len(samples) == 0 {
+		return samples
+	}
+
+	from, to := filter.window(samples[0].TimeStamp)
+	filtered := make([]LingoSample, 0, len(samples))
+	for _, sample := range samples {
+		if sample.TimeStamp < from || sample.TimeStamp > to {
+			continue
+		}
+
+		if filter.keep(sample.Label, sample.DataType, strconv.Itoa(sample.ResponseCode), sample.ThreadName) {
+			filtered = append(filtered, sample)
+		}
+	}
+
+	return filtered
+}
+
+// FilterCustomMetrics applies label filters to custom metric points. Points
+// without a label hold overall metrics and are kept.
+func FilterCustomMetrics(points []CustomMetricPoint, filter *Filter) []CustomMetricPoint {
+	filtered := make([]CustomMetricPoint, 0, len(points))
+	for _, point := range points {
+		if point.Label == "" || filter.keepLabel(point.Label) {
+			filtered = append(filtered, point)
+		}
+	}
+
+	return filtered
+}
+
+// FilterErrorEvents applies label filters to error events, eg. failed k6
+// checks labeled by check name.
+func FilterErrorEvents(events []ErrorEvent, filter *Filter) []ErrorEvent {
+	filtered := make([]ErrorEvent, 0, len(events))
+	for _, event := range events {
+		if event.Label == "" || filter.keepLabel(event.Label) {
+			filtered = append(filtered, event)
+		}
+	}
+
+	return filtered
+}
+
+// FilterBuckets applies time and label filters to pre-aggregated buckets,
+// which are kept when they start inside the window. Unlabeled buckets hold
+// overall metrics that include every label, so they are dropped when labels
+// are filtered and overall metrics are rolled up from the kept labels.
+func FilterBuckets(buckets []AggregatedBucket, filter *Filter) []AggregatedBucket {
+	if len(buckets) == 0 {
+		return buckets
+	}
+
+	startedAt, _ := AggregatedBucketsRange(buckets)
+	from, to := filter.window(startedAt)
+	filtersLabels := len(filter.includeLabels) > 0 || len(filter.excludeLabels) > 0
+
+	filtered := make([]AggregatedBucket, 0, len(buckets))
+	for _, bucket := range buckets {
+		if bucket.TimeStamp < from || bucket.TimeStamp > to {
+			continue
+		}
+
+		if bucket.Label == "" && !filtersLabels || bucket.Label != "" && filter.keepLabel(bucket.Label) {
+			filtered = append(filtered, bucket)
+		}
+	}
+
+	return filtered
+}
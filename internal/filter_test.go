@@ -0,0 +1,76 @@
+package internal
+
+import "testing"
+
+func TestFilterDataPoints(t *testing.T) {
+	filter, err := NewFilter(FilterConfig{
+		ExcludeLabels:    []string{"^health$"},
+		SkipFirst:        "10s",
+		To:               "30s",
+		ExcludeDataTypes: []string{"bin"},
+	})
+	if err != nil {
+		t.Error("Failed to build filter: ", err)
+	}
+
+	rows := []UngroupedMetricDataPoint{
+		{TimeStamp: 1000000, Label: "warm-up"},
+		{TimeStamp: 1010000, Label: "kept"},
+		{TimeStamp: 1015000, Label: "health"},
+		{TimeStamp: 1020000, Label: "binary", DataType: "bin"},
+		{TimeStamp: 1030000, Label: "kept"},
+		{TimeStamp: 1031000, Label: "cool-down"},
+	}
+
+	filtered := FilterDataPoints(rows, filter)
+	if len(filtered) != 2 {
+		t.Error("Failed to filter rows: ", len(filtered), " expected: ", 2)
+	}
+
+	for _, row := range filtered {
+		if row.Label != "kept" {
+			t.Error("Failed to filter row: ", row.Label)
+		}
+	}
+}
+
+func TestFilterErrorEvents(t *testing.T) {
+	filter, err := NewFilter(FilterConfig{ExcludeLabels: []string{"^health"}})
+	if err != nil {
+		t.Error("Failed to build filter: ", err)
+	}
+
+	events := []ErrorEvent{{Label: "health check"}, {Label: "checkout"}, {Label: ""}}
+	filtered := FilterErrorEvents(events, filter)
+	if len(filtered) != 2 || filtered[0].Label != "checkout" {
+		t.Error("Failed to filter error events: ", filtered)
+	}
+}
+
+func TestFilterBuckets(t *testing.T) {
+	buckets := []AggregatedBucket{
+		{TimeStamp: 1000000, Label: "checkout", Requests: 10},
+		{TimeStamp: 1000000, Requests: 15},
+		{TimeStamp: 1000000, Label: "health check", Requests: 5},
+		{TimeStamp: 1060000, Label: "checkout", Requests: 10},
+		{TimeStamp: 1060000, Requests: 10},
+	}
+
+	filter, err := NewFilter(FilterConfig{SkipFirst: "1m"})
+	if err != nil {
+		t.Error("Failed to build filter: ", err)
+	}
+	if filtered := FilterBuckets(buckets, filter); len(filtered) != 2 || filtered[0].TimeStamp != 1060000 {
+		t.Error("Failed to skip warm-up buckets: ", filtered)
+	}
+
+	// overall buckets include the excluded label, so they are dropped
+	filter, err = NewFilter(FilterConfig{ExcludeLabels: []string{"^health"}})
+	if err != nil {
+		t.Error("Failed to build filter: ", err)
+	}
+	filtered := FilterBuckets(buckets, filter)
+	if len(filtered) != 2 || filtered[0].Label != "checkout" || filtered[1].Label != "checkout" {
+		t.Error("Failed to filter bucket labels: ", filtered)
+	}
+}
@@ -0,0 +1,242 @@
+package internal
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const OtherLabel = "other"
+
+type LabelRewrite struct {
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement"`
+}
+
+// LabelRules describes how raw labels are normalized before reduction. Rules
+// are applied in order: regex rewrites, path templating, then aliases.
+type LabelRules struct {
+	Rewrites []LabelRewrite `json:"rewrites"`
+	// TemplatePaths replaces numeric and UUID path segments with {id}.
+	TemplatePaths bool              `json:"templatePaths"`
+	Aliases       map[string]string `json:"aliases"`
+	// MaxLabels folds the least requested labels into "other" once exceeded.
+	MaxLabels int `json:"maxLabels"`
+}
+
+type compiledRewrite struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+type LabelNormalizer struct {
+	rewrites      []compiledRewrite
+	templatePaths bool
+	aliases       map[string]string
+	maxLabels     int
+}
+
+var (
+	numericSegment = regexp.MustCompile(`^[0-9]+$`)
+	uuidSegment    = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+func NewLabelNormalizer(rules LabelRules) (*LabelNormalizer, error) {
+	normalizer := &LabelNormalizer{
+		templatePaths: rules.TemplatePaths,
+		aliases:       rules.Aliases,
+		maxLabels:     rules.MaxLabels,
+	}
+
+	for _, rewrite := range rules.Rewrites {
+		pattern, err := regexp.Compile(rewrite.Pattern)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid label rewrite pattern %s", rewrite.Pattern)
+		}
+		normalizer.rewrites = append(normalizer.rewrites, compiledRewrite{pattern, rewrite.Replacement})
+	}
+
+	return normalizer, nil
+}
+
+func (n *LabelNormalizer) Normalize(label string) string {
+	for _, rewrite := range n.rewrites {
+		label = rewrite.pattern.ReplaceAllString(label, rewrite.replacement)
+	}
+
+	if n.templatePaths {
+		label = templatePath(label)
+	}
+
+	if alias, ok := n.aliases[label]; ok {
+		label = alias
+	}
+
+	return label
+}
+
+func templatePath(label string) string {
+	segments := strings.Split(label, "/")
+	for i, segment := range segments {
+		path, query := segment, ""
+		if index := strings.IndexAny(segment, "?#"); index != -1 {
+			path, query = segment[:index], segment[index:]
+		}
+
+		if numericSegment.MatchString(path) || uuidSegment.MatchString(path) {
+			segments[i] = "{id}" + query
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// NormalizeDataPointLabels returns the labels kept under the cardinality cap,
+// or nil when no labels were folded.
+func NormalizeDataPointLabels(rows []UngroupedMetricDataPoint, normalizer *LabelNormalizer) map[string]bool {
+	counts := make(map[string]uint64)
+	for i := range rows {
+		rows[i].Label = normalizer.Normalize(rows[i].Label)
+		counts[rows[i].Label] += rows[i].Requests
+	}
+
+	kept := normalizer.keptLabels(counts)
+	if kept == nil {
+		return nil
+	}
+
+	for i := range rows {
+		if !kept[rows[i].Label] {
+			rows[i].Label = OtherLabel
+		}
+	}
+
+	return kept
+}
+
+// NormalizeBucketLabels normalizes and caps the labels of pre-aggregated
+// buckets like NormalizeDataPointLabels. Unlabeled buckets hold overall metrics
+// and are left as is.
+func NormalizeBucketLabels(buckets []AggregatedBucket, normalizer *LabelNormalizer) map[string]bool {
+	counts := make(map[string]uint64)
+	for i := range buckets {
+		if buckets[i].Label == "" {
+			continue
+		}
+		buckets[i].Label = normalizer.Normalize(buckets[i].Label)
+		counts[buckets[i].Label] += buckets[i].Requests
+	}
+
+	kept := normalizer.keptLabels(counts)
+	if kept == nil {
+		return nil
+	}
+
+	for i := range buckets {
+		if buckets[i].Label != "" && !kept[buckets[i].Label] {
+			buckets[i].Label = OtherLabel
+		}
+	}
+
+	return kept
+}
+
+// NormalizeSummaryLabels normalizes and caps the labels of summaries reported
+// by the tool. Summaries that end up with the same label are merged.
+func NormalizeSummaryLabels(summaryByLabel map[string]MetricSummary, normalizer *LabelNormalizer) map[string]MetricSummary {
+	counts := make(map[string]uint64)
+	for label, summary := range summaryByLabel {
+		counts[normalizer.Normalize(label)] += summary.TotalRequests
+	}
+	kept := normalizer.keptLabels(counts)
+
+	normalized := make(map[string]MetricSummary)
+	for label, summary := range summaryByLabel {
+		summary.Label = normalizer.normalizeKept(label, kept)
+		if existing, ok := normalized[summary.Label]; ok {
+			summary = MergeMetricSummaries(existing, summary)
+		}
+		normalized[summary.Label] = summary
+	}
+
+	return normalized
+}
+
+// NormalizeCustomMetricLabels normalizes labels like the rows of the run, and
+// folds labels that weren't kept for the rows into "other", so custom metrics
+// can't exceed the cardinality cap.
+func NormalizeCustomMetricLabels(points []CustomMetricPoint, normalizer *LabelNormalizer, kept map[string]bool) {
+	for i := range points {
+		points[i].Label = normalizer.normalizeKept(points[i].Label, kept)
+	}
+}
+
+// NormalizeErrorEventLabels normalizes error event labels like
+// NormalizeCustomMetricLabels.
+func NormalizeErrorEventLabels(events []ErrorEvent, normalizer *LabelNormalizer, kept map[string]bool) {
+	for i := range events {
+		events[i].Label = normalizer.normalizeKept(events[i].Label, kept)
+	}
+}
+
+func (n *LabelNormalizer) normalizeKept(label string, kept map[string]bool) string {
+	// unlabeled points hold overall metrics
+	if label == "" {
+		return label
+	}
+
+	label = n.Normalize(label)
+	if kept != nil && !kept[label] {
+		return OtherLabel
+	}
+	return label
+}
+
+func NormalizeSampleLabels(samples []LingoSample, normalizer *LabelNormalizer) {
+	counts := make(map[string]uint64)
+	for i := range samples {
+		samples[i].Label = normalizer.Normalize(samples[i].Label)
+		counts[samples[i].Label]++
+	}
+
+	kept := normalizer.keptLabels(counts)
+	if kept == nil {
+		return
+	}
+
+	for i := range samples {
+		if !kept[samples[i].Label] {
+			samples[i].Label = OtherLabel
+		}
+	}
+}
+
+// keptLabels returns the most requested labels that fit under the cardinality
+// cap, or nil when no labels need to be folded.
+func (n *LabelNormalizer) keptLabels(counts map[string]uint64) map[string]bool {
+	if n.maxLabels <= 0 || len(counts) <= n.maxLabels {
+		return nil
+	}
+
+	labels := make([]string, 0, len(counts))
+	for label := range counts {
+		labels = append(labels, label)
+	}
+
+	sort.Slice(labels, func(i int, j int) bool {
+		if counts[labels[i]] == counts[labels[j]] {
+			return labels[i] < labels[j]
+		}
+		return counts[labels[i]] > counts[labels[j]]
+	})
+
+	// reserve one slot for the folded labels
+	kept := make(map[string]bool)
+	for _, label := range labels[:n.maxLabels-1] {
+		kept[label] = true
+	}
+
+	return kept
+}
@@ -0,0 +1,117 @@
+package internal
+
+import "testing"
+
+func TestNormalizeLabel(t *testing.T) {
+	normalizer, err := NewLabelNormalizer(LabelRules{
+		Rewrites:      []LabelRewrite{{Pattern: `\?.*$`, Replacement: ""}},
+		TemplatePaths: true,
+		Aliases:       map[string]string{"GET /health": "health check"},
+	})
+	if err != nil {
+		t.Error("Failed to build label normalizer: ", err)
+	}
+
+	cases := map[string]string{
+		"GET /orders/123":        "GET /orders/{id}",
+		"GET /orders/456?page=2": "GET /orders/{id}",
+		"GET /users/0b7e9c5a-3f4d-4b8e-9a3c-1d2e3f4a5b6c/cart": "GET /users/{id}/cart",
+		"GET /health?verbose=true":                             "health check",
+		"POST /orders":                                         "POST /orders",
+	}
+
+	for label, expected := range cases {
+		if result := normalizer.Normalize(label); result != expected {
+			t.Error("Failed to normalize label: ", label, " result: ", result, " expected: ", expected)
+		}
+	}
+}
+
+func TestNormalizeDataPointLabelsCardinality(t *testing.T) {
+	normalizer, err := NewLabelNormalizer(LabelRules{MaxLabels: 2})
+	if err != nil {
+		t.Error("Failed to build label normalizer: ", err)
+	}
+
+	rows := []UngroupedMetricDataPoint{
+		{Label: "a", Requests: 1},
+		{Label: "a", Requests: 1},
+		{Label: "b", Requests: 1},
+		{Label: "c", Requests: 1},
+	}
+	NormalizeDataPointLabels(rows, normalizer)
+
+	expected := []string{"a", "a", OtherLabel, OtherLabel}
+	for i, row := range rows {
+		if row.Label != expected[i] {
+			t.Error("Failed to fold label: ", row.Label, " expected: ", expected[i])
+		}
+	}
+}
+
+func TestNormalizeCustomMetricLabelsFollowsCardinality(t *testing.T) {
+	normalizer, err := NewLabelNormalizer(LabelRules{MaxLabels: 2})
+	if err != nil {
+		t.Error("Failed to build label normalizer: ", err)
+	}
+
+	rows := []UngroupedMetricDataPoint{
+		{Label: "a", Requests: 2},
+		{Label: "b", Requests: 1},
+		{Label: "c", Requests: 1},
+	}
+	kept := NormalizeDataPointLabels(rows, normalizer)
+
+	points := []CustomMetricPoint{{Label: "a"}, {Label: "c"}, {Label: "status is 200"}, {Label: ""}}
+	NormalizeCustomMetricLabels(points, normalizer, kept)
+
+	expected := []string{"a", OtherLabel, OtherLabel, ""}
+	for i, point := range points {
+		if point.Label != expected[i] {
+			t.Error("Failed to fold custom metric label: ", point.Label, " expected: ", expected[i])
+		}
+	}
+}
+
+func TestNormalizeBucketLabelsCardinality(t *testing.T) {
+	normalizer, err := NewLabelNormalizer(LabelRules{MaxLabels: 2})
+	if err != nil {
+		t.Error("Failed to build label normalizer: ", err)
+	}
+
+	buckets := []AggregatedBucket{
+		{Label: "a", Requests: 2},
+		{Label: "b", Requests: 1},
+		{Label: "c", Requests: 1},
+		{Requests: 4},
+	}
+	NormalizeBucketLabels(buckets, normalizer)
+
+	expected := []string{"a", OtherLabel, OtherLabel, ""}
+	for i, bucket := range buckets {
+		if bucket.Label != expected[i] {
+			t.Error("Failed to fold bucket label: ", bucket.Label, " expected: ", expected[i])
+		}
+	}
+}
+
+func TestNormalizeSummaryLabelsMergesFoldedLabels(t *testing.T) {
+	normalizer, err := NewLabelNormalizer(LabelRules{MaxLabels: 2})
+	if err != nil {
+		t.Error("Failed to build label normalizer: ", err)
+	}
+
+	summaries := NormalizeSummaryLabels(map[string]MetricSummary{
+		"a": {Label: "a", TotalRequests: 20},
+		"b": {Label: "b", TotalRequests: 5},
+		"c": {Label: "c", TotalRequests: 3},
+	}, normalizer)
+
+	if len(summaries) != 2 || summaries["a"].TotalRequests != 20 || summaries[OtherLabel].TotalRequests != 8 {
+		t.Error("Failed to fold summary labels: ", summaries)
+	}
+
+	if summaries[OtherLabel].Label != OtherLabel {
+		t.Error("Failed to relabel folded summary: ", summaries[OtherLabel].Label)
+	}
+}
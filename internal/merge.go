@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+const (
+	GeneratorFromFile   = "file"
+	GeneratorFromThread = "thread"
+)
+
+// InferGenerator names the load generator that produced a row. JMeter prefixes
+// thread names with the host in distributed mode, eg. "loadgen1-Thread Group
+// 1-1", otherwise the file name is used.
+func InferGenerator(file string, threadName string, generatorFrom string) string {
+	if generatorFrom == GeneratorFromThread {
+		if host, ok := threadNameHost(threadName); ok {
+			return host
+		}
+	}
+
+	return filepath.Base(file)
+}
+
+func threadNameHost(threadName string) (string, bool) {
+	index := strings.Index(threadName, "-")
+	if index <= 0 {
+		return "", false
+	}
+
+	host, rest := threadName[:index], threadName[index+1:]
+	if strings.Contains(host, " ") || !strings.Contains(rest, " ") {
+		return "", false
+	}
+
+	return host, true
+}
+
+// MergeDataPoints merges time sorted streams into a single time sorted stream.
+func MergeDataPoints(streams [][]UngroupedMetricDataPoint) []UngroupedMetricDataPoint {
+	var (
+		total   int
+		merged  []UngroupedMetricDataPoint
+		offsets = make([]int, len(streams))
+	)
+
+	for _, stream := range streams {
+		total += len(stream)
+	}
+	merged = make([]UngroupedMetricDataPoint, 0, total)
+
+	for len(merged) < total {
+		next := -1
+		for i, stream := range streams {
+			if offsets[i] == len(stream) {
+				continue
+			}
+			if next == -1 || stream[offsets[i]].TimeStamp < streams[next][offsets[next]].TimeStamp {
+				next = i
+			}
+		}
+
+		merged = append(merged, streams[next][offsets[next]])
+		offsets[next]++
+	}
+
+	return merged
+}
+
+// MergeSamples merges time sorted streams into a single time sorted stream.
+func MergeSamples(streams [][]LingoSample) []LingoSample {
+	var (
+		total   int
+		merged  []LingoSample
+		offsets = make([]int, len(streams))
+	)
+
+	for _, stream := range streams {
+		total += len(stream)
+	}
+	merged = make([]LingoSample, 0, total)
+
+	for len(merged) < total {
+		next := -1
+		for i, stream := range streams {
+			if offsets[i] == len(stream) {
+				continue
+			}
+			if next == -1 || stream[offsets[i]].TimeStamp < streams[next][offsets[next]].TimeStamp {
+				next = i
+			}
+		}
+
+		merged = append(merged, streams[next][offsets[next]])
+		offsets[next]++
+	}
+
+	return merged
+}
@@ -3,23 +3,134 @@ package internal
 import (
 	"fmt"
 	"os"
+	"sort"
 )
 
 const MaxFileSize = 1000 * 1000 * 100 // 100MB
 
-func ParseDataFile(file string, format string) ([]UngroupedMetricDataPoint, error) {
-	validateFile(file)
-
-	switch format {
-	case "jmeter":
-		return ParseDataFileJmeter(file)
-	case "k6":
-		return ParseDataFileK6(file)
-	case "gatling":
-		return ParseDataFileGatling(file)
-	default:
-		return nil, fmt.Errorf("unsupported format %s", format)
+type ParseOptions struct {
+	// LabelBy selects the tag used to label requests, eg. name, group or
+	// scenario for k6. Formats use their own default when empty.
+	LabelBy string
+	// Samples asks for every sample instead of rows, for --all-samples.
+	Samples bool
+	// DefaultLabel labels requests of formats that don't report what was
+	// requested, eg. hey. It is the run's --label.
+	DefaultLabel string
+	// StartedAt is the start of the run in milliseconds, from --started-at,
+	// for formats without time stamps, eg. k6 summaries. It is zero when not
+	// passed.
+	StartedAt uint64
+}
+
+// ParseStartedAt reads --started-at, a timestamp in any of the formats
+// accepted for time stamps, eg. 2022-03-17T10:00:12Z or epoch seconds.
+func ParseStartedAt(value string) (uint64, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	startedAt, ok := tryParseTimeStampMillis(value)
+	if !ok {
+		return 0, fmt.Errorf("invalid start time %s, expected a timestamp like 2022-03-17T10:00:12Z", value)
+	}
+	return startedAt, nil
+}
+
+type ParsedData struct {
+	Rows    []UngroupedMetricDataPoint
+	Samples []LingoSample
+	// Buckets holds requests aggregated by the tool, for formats that report
+	// periods instead of requests, eg. Artillery.
+	Buckets       []AggregatedBucket
+	CustomMetrics []CustomMetricPoint
+	// Summary is set instead of rows for formats that report their own totals.
+	Summary  *SummaryData
+	Metadata *RunMetadata
+	// Errors holds failures that aren't attached to a row, eg. failed k6 checks.
+	Errors []ErrorEvent
+}
+
+// RunMetadata holds details about the run reported by the load test tool.
+// Time stamps are in milliseconds.
+type RunMetadata struct {
+	Name      string
+	StartedAt uint64
+}
+
+// SummaryData holds totals reported by the tool, for formats without
+// requests. Time stamps are in milliseconds.
+type SummaryData struct {
+	Summary        MetricSummary
+	SummaryByLabel map[string]MetricSummary
+	StartedAt      uint64
+	StoppedAt      uint64
+}
+
+// ParseDataFile reads a file with the parser registered for the format, or
+// the detected parser for FormatAuto.
+func ParseDataFile(file string, format string, options ParseOptions) (*ParsedData, error) {
+	if err := validateFile(file); err != nil {
+		return nil, err
+	}
+
+	var parser Parser
+	if format == FormatAuto {
+		detected, err := DetectParser(file)
+		if err != nil {
+			return nil, err
+		}
+		parser = detected
+	} else {
+		registered, ok := LookupParser(format)
+		if !ok {
+			return nil, fmt.Errorf("unsupported format %s", format)
+		}
+		parser = registered
+	}
+
+	parsed := &ParsedData{}
+	if err := parser.Parse(file, options, parsed); err != nil {
+		return nil, err
 	}
+
+	// parsers registered by other programs may not sort what they read
+	sort.SliceStable(parsed.Rows, func(i int, j int) bool {
+		return parsed.Rows[i].TimeStamp < parsed.Rows[j].TimeStamp
+	})
+	sort.SliceStable(parsed.Samples, func(i int, j int) bool {
+		return parsed.Samples[i].TimeStamp < parsed.Samples[j].TimeStamp
+	})
+
+	return parsed, nil
+}
+
+func (d *ParsedData) AddRow(row UngroupedMetricDataPoint) {
+	d.Rows = append(d.Rows, row)
+}
+
+func (d *ParsedData) AddSample(sample LingoSample) {
+	d.Samples = append(d.Samples, sample)
+}
+
+func (d *ParsedData) AddBucket(bucket AggregatedBucket) {
+	d.Buckets = append(d.Buckets, bucket)
+}
+
+func (d *ParsedData) AddCustomMetric(point CustomMetricPoint) {
+	d.CustomMetrics = append(d.CustomMetrics, point)
+}
+
+func (d *ParsedData) AddError(event ErrorEvent) {
+	d.Errors = append(d.Errors, event)
+}
+
+func (d *ParsedData) SetMetadata(metadata *RunMetadata) {
+	d.Metadata = metadata
+}
+
+func (d *ParsedData) SetSummary(summary *SummaryData) {
+	d.Summary = summary
 }
 
 func validateFile(file string) error {
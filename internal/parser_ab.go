@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// ParseDataFileAb reads the gnuplot file written by ab -g. ab sorts it by
+// total time, so rows are sorted by start time.
+func ParseDataFileAb(file string, options ParseOptions) (*ParsedData, error) {
+	if err := validateFile(file); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot open file %s", file)
+	}
+
+	defer f.Close()
+
+	csvReader := csv.NewReader(f)
+	csvReader.Comma = '\t'
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read file %s", file)
+	}
+
+	indices, err := BuildColumnIndicesAb(header)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot parse file %s", file)
+	}
+
+	parsed := &ParsedData{}
+	for {
+		rec, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "cannot read file %s", file)
+		}
+
+		request, err := ParseAbRow(rec, indices)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot parse file %s", file)
+		}
+
+		if options.Samples {
+			parsed.Samples = append(parsed.Samples, TranslateAbSample(request, options.DefaultLabel))
+		} else {
+			parsed.Rows = append(parsed.Rows, TranslateAbRow(request, options.DefaultLabel))
+		}
+	}
+
+	if options.Samples {
+		sort.SliceStable(parsed.Samples, func(i int, j int) bool {
+			return parsed.Samples[i].TimeStamp < parsed.Samples[j].TimeStamp
+		})
+		return parsed, nil
+	}
+
+	// ab doesn't write its concurrency, and start times in whole seconds are
+	// too coarse to estimate it, so virtual users are left empty
+	sort.SliceStable(parsed.Rows, func(i int, j int) bool {
+		return parsed.Rows[i].TimeStamp < parsed.Rows[j].TimeStamp
+	})
+
+	return parsed, nil
+}
@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ParseDataFileArtillery reads the report written by artillery run --output.
+// Periods are already aggregated, so they are published as chart metrics with
+// the percentiles Artillery reported instead of being recomputed.
+func ParseDataFileArtillery(file string) (*ParsedData, error) {
+	if err := validateFile(file); err != nil {
+		return nil, err
+	}
+
+	contents, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read file %s", file)
+	}
+
+	var report ArtilleryReport
+	if err := json.Unmarshal(contents, &report); err != nil {
+		return nil, errors.Wrapf(err, "cannot parse file %s", file)
+	}
+
+	if len(report.Intermediate) == 0 {
+		return nil, errors.Errorf("cannot parse file %s: no intermediate periods found", file)
+	}
+
+	return TranslateArtilleryReport(report), nil
+}
+
+func TranslateArtilleryReport(report ArtilleryReport) *ParsedData {
+	periods := report.Intermediate
+	sort.SliceStable(periods, func(i int, j int) bool {
+		return periods[i].StartedAt() < periods[j].StartedAt()
+	})
+
+	duration := artilleryPeriodDuration(periods)
+
+	var buckets []AggregatedBucket
+	for _, period := range periods {
+		buckets = append(buckets, TranslateArtilleryPeriod(period, duration)...)
+	}
+
+	summary, summaryByLabel := SummarizeAggregatedBuckets(buckets)
+	applyArtilleryAggregate(&summary, report.Aggregate, "")
+	for label, labelSummary := range summaryByLabel {
+		applyArtilleryAggregate(&labelSummary, report.Aggregate, label)
+		summaryByLabel[label] = labelSummary
+	}
+
+	startedAt, stoppedAt := AggregatedBucketsRange(buckets)
+	if report.Aggregate.LastMetricAt > startedAt {
+		stoppedAt = report.Aggregate.LastMetricAt
+	}
+
+	return &ParsedData{
+		Buckets: buckets,
+		Summary: &SummaryData{
+			Summary:        summary,
+			SummaryByLabel: summaryByLabel,
+			StartedAt:      startedAt,
+			StoppedAt:      stoppedAt,
+		},
+	}
+}
+
+// artilleryPeriodDuration is the smallest gap between sorted periods. Periods
+// without requests aren't reported, so other gaps are multiples of it. When
+// they aren't, the report doesn't have a regular period and the default is
+// used.
+func artilleryPeriodDuration(periods []ArtilleryPeriod) time.Duration {
+	var gaps []uint64
+	var smallest uint64
+	for i := 1; i < len(periods); i++ {
+		gap := periods[i].StartedAt() - periods[i-1].StartedAt()
+		if gap == 0 {
+			continue
+		}
+		gaps = append(gaps, gap)
+		if smallest == 0 || gap < smallest {
+			smallest = gap
+		}
+	}
+
+	if smallest == 0 {
+		return artilleryDefaultPeriod
+	}
+	for _, gap := range gaps {
+		if gap%smallest != 0 {
+			return artilleryDefaultPeriod
+		}
+	}
+	return time.Duration(smallest) * time.Millisecond
+}
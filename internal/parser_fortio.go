@@ -0,0 +1,38 @@
+package internal
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+// ParseDataFileFortio reads the JSON result of Fortio. It only holds totals
+// and histograms, so the run is published as a summary labeled by URL.
+func ParseDataFileFortio(file string, options ParseOptions) (*ParsedData, error) {
+	if err := validateFile(file); err != nil {
+		return nil, err
+	}
+
+	contents, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read file %s", file)
+	}
+
+	var result FortioResult
+	if err := json.Unmarshal(contents, &result); err != nil {
+		return nil, errors.Wrapf(err, "cannot parse file %s", file)
+	}
+
+	if result.DurationHistogram.Count == 0 {
+		return nil, errors.Errorf("cannot parse file %s: no requests found", file)
+	}
+
+	label := FortioLabel(result)
+	if label == "" {
+		label = options.DefaultLabel
+	}
+
+	summary := TranslateFortioSummary(result, label)
+	return &ParsedData{Summary: &summary}, nil
+}
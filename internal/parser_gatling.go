@@ -2,6 +2,7 @@ package internal
 
 import (
 	"bufio"
+	"log"
 	"os"
 	"sort"
 	"strings"
@@ -9,11 +10,16 @@ import (
 	"github.com/pkg/errors"
 )
 
-func ParseDataFileGatling(file string) ([]UngroupedMetricDataPoint, error) {
+func ParseDataFileGatling(file string) (*ParsedData, error) {
 	// REQUEST		GET low latency	1647457744634	1647457744825	OK
 
 	var (
-		rows []UngroupedMetricDataPoint
+		rows        []UngroupedMetricDataPoint
+		starts      []uint64
+		userEvents  []UserEvent
+		metadata    *RunMetadata
+		errorEvents []ErrorEvent
+		skipped     int
 	)
 
 	if err := validateFile(file); err != nil {
@@ -31,14 +37,55 @@ func ParseDataFileGatling(file string) ([]UngroupedMetricDataPoint, error) {
 	for scanner.Scan() {
 		line := scanner.Text()
 		row := strings.Split(line, "\t")
-		if row[0] == "REQUEST" {
-			rows = append(rows, TranslateGatlingRow(row))
+		switch row[0] {
+		case "REQUEST", "GROUP":
+			var (
+				parsed UngroupedMetricDataPoint
+				ok     bool
+			)
+			if row[0] == "REQUEST" {
+				parsed, ok = TranslateGatlingRow(row)
+			} else {
+				parsed, ok = TranslateGatlingGroupRow(row)
+			}
+
+			if !ok {
+				skipped++
+				continue
+			}
+
+			start, _, _ := gatlingTimeColumns(row)
+			rows = append(rows, parsed)
+			starts = append(starts, ParseTimeStampMillis(row[start]))
+		case "USER":
+			if event, ok := TranslateGatlingUserRow(row); ok {
+				userEvents = append(userEvents, event)
+			}
+		case "RUN":
+			if parsed, ok := TranslateGatlingRunRow(row); ok {
+				metadata = parsed
+			}
+		case "ERROR":
+			// crashes and assertion errors outside of requests
+			if event, ok := TranslateGatlingErrorRow(row); ok {
+				errorEvents = append(errorEvents, event)
+			}
 		}
 	}
 
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "cannot read file %s", file)
+	}
+
+	if skipped > 0 {
+		log.Println("skipped", skipped, "malformed records in", file)
+	}
+
+	applyVirtualUsers(rows, starts, VirtualUserSeriesFromEvents(userEvents))
+
 	sort.SliceStable(rows, func(i int, j int) bool {
 		return rows[i].TimeStamp < rows[j].TimeStamp
 	})
 
-	return rows, nil
+	return &ParsedData{Rows: rows, Metadata: metadata, Errors: errorEvents}, nil
 }
@@ -0,0 +1,70 @@
+package internal
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// ParseDataFileGhz reads the JSON report of ghz. Calls in details are
+// published like HTTP requests, labeled by method. Reports without details
+// are published as a summary.
+func ParseDataFileGhz(file string, options ParseOptions) (*ParsedData, error) {
+	if err := validateFile(file); err != nil {
+		return nil, err
+	}
+
+	contents, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read file %s", file)
+	}
+
+	var report GhzReport
+	if err := json.Unmarshal(contents, &report); err != nil {
+		return nil, errors.Wrapf(err, "cannot parse file %s", file)
+	}
+
+	label := GhzLabel(report.Options)
+	if label == "" {
+		label = options.DefaultLabel
+	}
+
+	if len(report.Details) == 0 {
+		if options.Samples {
+			return nil, errors.Errorf("cannot parse file %s: samples need the details of each call", file)
+		}
+
+		summary := TranslateGhzSummary(report)
+		labelSummary := summary
+		labelSummary.Label = label
+		labelSummary.Throughput = nil
+
+		startedAt := uint64(report.Date.UnixMilli())
+		return &ParsedData{Summary: &SummaryData{
+			Summary:        summary,
+			SummaryByLabel: map[string]MetricSummary{label: labelSummary},
+			StartedAt:      startedAt,
+			StoppedAt:      startedAt + uint64(report.Total.Milliseconds()),
+		}}, nil
+	}
+
+	parsed := &ParsedData{Metadata: &RunMetadata{StartedAt: uint64(report.Date.UnixMilli())}}
+	for _, detail := range report.Details {
+		if options.Samples {
+			parsed.Samples = append(parsed.Samples, TranslateGhzDetailSample(detail, label, report.Options.Concurrency))
+		} else {
+			parsed.Rows = append(parsed.Rows, TranslateGhzDetail(detail, label, report.Options.Concurrency))
+		}
+	}
+
+	sort.SliceStable(parsed.Rows, func(i int, j int) bool {
+		return parsed.Rows[i].TimeStamp < parsed.Rows[j].TimeStamp
+	})
+	sort.SliceStable(parsed.Samples, func(i int, j int) bool {
+		return parsed.Samples[i].TimeStamp < parsed.Samples[j].TimeStamp
+	})
+
+	return parsed, nil
+}
@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"encoding/csv"
+	"io"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// ParseDataFileHey reads hey -o csv output. Offsets are relative to the start
+// of the run, which isn't written. It is options.StartedAt when set, otherwise
+// the file is assumed to be written when the last request completed.
+func ParseDataFileHey(file string, options ParseOptions) (*ParsedData, error) {
+	if err := validateFile(file); err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot open file %s", file)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot open file %s", file)
+	}
+
+	defer f.Close()
+
+	csvReader := csv.NewReader(f)
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read file %s", file)
+	}
+
+	indices, err := BuildColumnIndicesHey(header)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot parse file %s", file)
+	}
+
+	var requests []HeyRequest
+	var runDuration float64
+	for {
+		rec, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "cannot read file %s", file)
+		}
+
+		request, err := ParseHeyRow(rec, indices)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot parse file %s", file)
+		}
+		requests = append(requests, request)
+
+		if end := request.Offset + request.ResponseTime; end > runDuration {
+			runDuration = end
+		}
+	}
+
+	startedAt := options.StartedAt
+	if startedAt == 0 {
+		log.Println("hey doesn't write the start of the run, deriving it from the modification time of", file, "- pass --started-at if the file was copied or edited")
+		startedAt = uint64(info.ModTime().UnixMilli()) - secondsToMillis(runDuration)
+	}
+	parsed := &ParsedData{Metadata: &RunMetadata{StartedAt: startedAt}}
+
+	if options.Samples {
+		for _, request := range requests {
+			parsed.Samples = append(parsed.Samples, TranslateHeySample(request, startedAt, options.DefaultLabel))
+		}
+		sort.SliceStable(parsed.Samples, func(i int, j int) bool {
+			return parsed.Samples[i].TimeStamp < parsed.Samples[j].TimeStamp
+		})
+		return parsed, nil
+	}
+
+	var starts []uint64
+	for _, request := range requests {
+		row := TranslateHeyRow(request, startedAt, options.DefaultLabel)
+		parsed.Rows = append(parsed.Rows, row)
+		starts = append(starts, row.TimeStamp)
+	}
+
+	// hey doesn't write its concurrency, so workers are estimated from requests in flight
+	applyVirtualUsers(parsed.Rows, starts, &VirtualUserSeries{})
+
+	sort.SliceStable(parsed.Rows, func(i int, j int) bool {
+		return parsed.Rows[i].TimeStamp < parsed.Rows[j].TimeStamp
+	})
+
+	return parsed, nil
+}
@@ -1,41 +1,53 @@
 package internal
 
 import (
+	"bufio"
 	"context"
 	"encoding/csv"
 	"io"
 	"os"
-	"sort"
 
 	"github.com/getsentry/sentry-go"
 	"github.com/pkg/errors"
 )
 
-func ParseDataFileJmeter(file string) ([]UngroupedMetricDataPoint, error) {
+// ParseDataFileJmeter adds the rows of JMeter CSV or XML results to the sink
+// as they are read. XML samples are added once their top level sample is
+// closed, since nested samples mark their parent as a transaction.
+func ParseDataFileJmeter(file string, sink Sink) error {
 	span := sentry.StartSpan(context.Background(), "ParseDataFile")
 	defer span.Finish()
 
-	var (
-		rows []UngroupedMetricDataPoint
-	)
-
 	f, err := os.Open(file)
 	if err != nil {
-		return nil, errors.Wrapf(err, "cannot open file %s", file)
+		return errors.Wrapf(err, "cannot open file %s", file)
 	}
 
 	defer f.Close()
 
-	csvReader := csv.NewReader(f)
+	reader := bufio.NewReader(f)
+	head, err := reader.Peek(1)
+	if err != nil && err != io.EOF {
+		return errors.Wrapf(err, "cannot read file %s", file)
+	}
+
+	if len(head) > 0 && head[0] == '<' {
+		if err := parseJmeterXml(reader, sink); err != nil {
+			return errors.Wrapf(err, "cannot parse file %s", file)
+		}
+		return nil
+	}
+
+	csvReader := csv.NewReader(reader)
 	header, err := csvReader.Read()
 	if err != nil {
-		return nil, errors.Wrapf(err, "cannot read file %s", file)
+		return errors.Wrapf(err, "cannot read file %s", file)
 	}
 
 	var indices *ColumnIndices
 	indices, err = BuildColumnIndices(header)
 	if err != nil {
-		return nil, errors.Wrapf(err, "cannot parse file %s", file)
+		return errors.Wrapf(err, "cannot parse file %s", file)
 	}
 
 	for {
@@ -43,33 +55,27 @@ func ParseDataFileJmeter(file string) ([]UngroupedMetricDataPoint, error) {
 		if err == io.EOF {
 			break
 		} else if err != nil {
-			return nil, errors.Wrapf(err, "cannot read file %s", file)
+			return errors.Wrapf(err, "cannot read file %s", file)
 		}
-		rows = append(rows, TranslateJmeterRow(rec, indices))
+		sink.AddRow(TranslateJmeterRow(rec, indices))
 	}
 
-	sort.SliceStable(rows, func(i int, j int) bool {
-		return rows[i].TimeStamp < rows[j].TimeStamp
-	})
-
-	return rows, nil
+	return nil
 }
 
-func ParseDataFileSamples(file string) ([]LingoSample, error) {
+// ParseDataFileSamples adds every sample of JMeter CSV results to the sink as
+// they are read.
+func ParseDataFileSamples(file string, sink Sink) error {
 	span := sentry.StartSpan(context.Background(), "ParseDataFileSamples")
 	defer span.Finish()
 
-	var (
-		samples []LingoSample
-	)
-
 	if err := validateFile(file); err != nil {
-		return nil, err
+		return err
 	}
 
 	f, err := os.Open(file)
 	if err != nil {
-		return nil, errors.Wrapf(err, "cannot open file %s", file)
+		return errors.Wrapf(err, "cannot open file %s", file)
 	}
 
 	defer f.Close()
@@ -77,12 +83,12 @@ func ParseDataFileSamples(file string) ([]LingoSample, error) {
 	csvReader := csv.NewReader(f)
 	header, err := csvReader.Read()
 	if err != nil {
-		return nil, errors.Wrapf(err, "cannot read file %s", file)
+		return errors.Wrapf(err, "cannot read file %s", file)
 	}
 
 	indices, err := BuildColumnIndicesV2(header)
 	if err != nil {
-		return nil, errors.Wrapf(err, "cannot parse file %s", file)
+		return errors.Wrapf(err, "cannot parse file %s", file)
 	}
 
 	for {
@@ -90,14 +96,10 @@ func ParseDataFileSamples(file string) ([]LingoSample, error) {
 		if err == io.EOF {
 			break
 		} else if err != nil {
-			return nil, errors.Wrapf(err, "cannot read file %s", file)
+			return errors.Wrapf(err, "cannot read file %s", file)
 		}
-		samples = append(samples, TranslateJmeterRowSample(rec, indices))
+		sink.AddSample(TranslateJmeterRowSample(rec, indices))
 	}
 
-	sort.SliceStable(samples, func(i int, j int) bool {
-		return samples[i].TimeStamp < samples[j].TimeStamp
-	})
-
-	return samples, nil
+	return nil
 }
@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+// parseJmeterXml reads JMeter XML results. A sample element without a data
+// type that holds other samples is the parent sample of a Transaction
+// Controller with "Generate parent sample" enabled. httpSample elements can
+// also hold samples, eg. redirects, and aren't transactions.
+func parseJmeterXml(reader io.Reader, sink Sink) error {
+	var (
+		// rows of the top level sample being read
+		rows []UngroupedMetricDataPoint
+		open []int
+		// whether each open sample is an httpSample
+		openHttp []bool
+	)
+
+	decoder := xml.NewDecoder(reader)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			switch element.Name.Local {
+			case "sample", "httpSample":
+				if len(open) > 0 && !openHttp[len(openHttp)-1] {
+					parent := &rows[open[len(open)-1]]
+					parent.Transaction = parent.Transaction || parent.DataType == ""
+				}
+				rows = append(rows, TranslateJmeterXmlSample(element.Attr))
+				open = append(open, len(rows)-1)
+				openHttp = append(openHttp, element.Name.Local == "httpSample")
+			case "assertionResult":
+				var assertion JmeterXmlAssertion
+				if err := decoder.DecodeElement(&assertion, &element); err != nil {
+					return err
+				}
+				if len(open) > 0 && (assertion.Failure || assertion.Error) {
+					row := &rows[open[len(open)-1]]
+					if row.AssertionName == "" {
+						row.AssertionName = assertion.Name
+						row.FailureMessage = assertion.FailureMessage
+					}
+				}
+			}
+		case xml.EndElement:
+			if (element.Name.Local == "sample" || element.Name.Local == "httpSample") && len(open) > 0 {
+				open = open[:len(open)-1]
+				openHttp = openHttp[:len(openHttp)-1]
+				if len(open) == 0 {
+					addRows(sink, rows)
+					rows = rows[:0]
+				}
+			}
+		}
+	}
+
+	return nil
+}
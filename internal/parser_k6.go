@@ -2,7 +2,9 @@ package internal
 
 import (
 	"bufio"
+	"encoding/csv"
 	"encoding/json"
+	"io"
 	"log"
 	"os"
 	"sort"
@@ -10,11 +12,144 @@ import (
 	"github.com/pkg/errors"
 )
 
-func ParseDataFileK6(file string) ([]UngroupedMetricDataPoint, error) {
-	var (
-		rows []UngroupedMetricDataPoint
-	)
+// k6 built-in metrics published as custom metrics, in addition to any metric
+// declared by the test script.
+var k6CustomMetrics = map[string]string{
+	"http_req_failed":          CustomMetricRate,
+	"http_req_waiting":         CustomMetricTrend,
+	"http_req_connecting":      CustomMetricTrend,
+	"http_req_tls_handshaking": CustomMetricTrend,
+	"data_received":            CustomMetricCounter,
+	"data_sent":                CustomMetricCounter,
+	"iteration_duration":       CustomMetricTrend,
+	"checks":                   CustomMetricRate,
+}
+
+// k6 built-in metrics that are either parsed separately or not published.
+var k6BuiltinMetrics = map[string]bool{
+	"http_req_duration":  true,
+	"http_req_blocked":   true,
+	"http_req_sending":   true,
+	"http_req_receiving": true,
+	"http_reqs":          true,
+	"iterations":         true,
+	"dropped_iterations": true,
+	"group_duration":     true,
+	"vus":                true,
+	"vus_max":            true,
+}
+
+// k6Collector turns k6 metric samples into rows and custom metrics, regardless
+// of the output format they were read from.
+type k6Collector struct {
+	options       ParseOptions
+	rows          []UngroupedMetricDataPoint
+	bandwidth     []UngroupedMetricDataPoint
+	customMetrics []CustomMetricPoint
+	errorEvents   []ErrorEvent
+	starts        []uint64
+	// lastRequest is the row timing points of the same request are added to
+	lastRequest  int
+	virtualUsers *VirtualUserSeries
+	metricTypes  map[string]string
+}
+
+func newK6Collector(options ParseOptions) *k6Collector {
+	return &k6Collector{
+		options:      options,
+		lastRequest:  -1,
+		virtualUsers: &VirtualUserSeries{},
+		metricTypes:  make(map[string]string),
+	}
+}
+
+func (c *k6Collector) add(metric K6Metric) {
+	if metric.Type == "Metric" {
+		c.metricTypes[metric.Data.Name] = metric.Data.Type
+		return
+	}
+
+	if metric.Type != "Point" {
+		return
+	}
+
+	switch metric.Metric {
+	case "http_req_duration":
+		row := TranslateK6Row(metric)
+		row.Label = K6Label(metric.Data.Tags, c.options.LabelBy)
+		c.rows = append(c.rows, row)
+		c.lastRequest = len(c.rows) - 1
+		// k6 stamps http samples with the time the request finished
+		c.starts = append(c.starts, ParseTimeStampMillis(metric.Data.Time)-uint64(metric.Data.Value))
+	case "vus":
+		c.virtualUsers.Add(ParseTimeStampMillis(metric.Data.Time), uint64(metric.Data.Value))
+	default:
+		metricType, ok := k6CustomMetrics[metric.Metric]
+		if !ok && !k6BuiltinMetrics[metric.Metric] {
+			metricType, ok = c.metricTypes[metric.Metric]
+		}
+
+		if ok {
+			c.customMetrics = append(c.customMetrics, TranslateK6CustomMetric(metric, metricType, c.options.LabelBy))
+		}
+
+		if metric.Metric == "http_req_connecting" || metric.Metric == "http_req_waiting" {
+			c.addTiming(metric)
+		}
+
+		if metric.Metric == "data_received" || metric.Metric == "data_sent" {
+			c.bandwidth = append(c.bandwidth, TranslateK6BandwidthRow(metric, c.options.LabelBy))
+		}
 
+		if metric.Metric == "checks" && metric.Data.Value == 0 {
+			c.errorEvents = append(c.errorEvents, ErrorEvent{
+				TimeStamp: ParseTimeStampMillis(metric.Data.Time),
+				Label:     K6Label(metric.Data.Tags, c.options.LabelBy),
+				Category:  ErrorCategoryAssertion,
+				Value:     metric.Data.Tags.Check,
+			})
+		}
+	}
+}
+
+// addTiming adds a timing point to the row of its request. k6 writes the
+// metrics of a request one after another, with the same time and tags.
+func (c *k6Collector) addTiming(metric K6Metric) {
+	if c.lastRequest == -1 {
+		return
+	}
+
+	row := &c.rows[c.lastRequest]
+	if row.TimeStamp != ParseTimeStampMillis(metric.Data.Time) || row.Label != K6Label(metric.Data.Tags, c.options.LabelBy) {
+		return
+	}
+
+	row.HasTimings = true
+	if metric.Metric == "http_req_connecting" {
+		row.ConnectTime = uint64(metric.Data.Value)
+	} else {
+		row.ServerLatency = uint64(metric.Data.Value)
+	}
+}
+
+func (c *k6Collector) result() *ParsedData {
+	applyVirtualUsers(c.rows, c.starts, c.virtualUsers)
+	// bandwidth rows carry no requests, so they are kept out of the estimate
+	c.rows = append(c.rows, c.bandwidth...)
+
+	sort.SliceStable(c.rows, func(i int, j int) bool {
+		return c.rows[i].TimeStamp < c.rows[j].TimeStamp
+	})
+
+	sort.SliceStable(c.customMetrics, func(i int, j int) bool {
+		return c.customMetrics[i].TimeStamp < c.customMetrics[j].TimeStamp
+	})
+
+	return &ParsedData{Rows: c.rows, CustomMetrics: c.customMetrics, Errors: c.errorEvents}
+}
+
+// ParseDataFileK6 reads k6 JSON output (--out json=) or CSV output (--out csv=).
+func ParseDataFileK6(file string, options ParseOptions) (*ParsedData, error) {
 	if err := validateFile(file); err != nil {
 		return nil, err
 	}
@@ -26,7 +161,22 @@ func ParseDataFileK6(file string) ([]UngroupedMetricDataPoint, error) {
 
 	defer f.Close()
 
-	scanner := bufio.NewScanner(f)
+	reader := bufio.NewReader(f)
+	collector := newK6Collector(options)
+
+	head, err := reader.Peek(1)
+	if err != nil && err != io.EOF {
+		return nil, errors.Wrapf(err, "cannot read file %s", file)
+	}
+
+	if len(head) > 0 && head[0] != '{' {
+		if err := parseK6Csv(reader, collector); err != nil {
+			return nil, errors.Wrapf(err, "cannot parse file %s", file)
+		}
+		return collector.result(), nil
+	}
+
+	scanner := bufio.NewScanner(reader)
 	for scanner.Scan() {
 		var metric K6Metric
 		line := scanner.Bytes()
@@ -34,9 +184,7 @@ func ParseDataFileK6(file string) ([]UngroupedMetricDataPoint, error) {
 			return nil, errors.Wrapf(err, "cannot parse line %s", line)
 		}
 
-		if metric.Type == "Point" && metric.Metric == "http_req_duration" {
-			rows = append(rows, TranslateK6Row(metric))
-		}
+		collector.add(metric)
 	}
 
 	// Check for errors during scanning.
@@ -45,9 +193,34 @@ func ParseDataFileK6(file string) ([]UngroupedMetricDataPoint, error) {
 		return nil, errors.Wrapf(err, "cannot read file %s", file)
 	}
 
-	sort.SliceStable(rows, func(i int, j int) bool {
-		return rows[i].TimeStamp < rows[j].TimeStamp
-	})
+	return collector.result(), nil
+}
+
+func parseK6Csv(reader io.Reader, collector *k6Collector) error {
+	csvReader := csv.NewReader(reader)
+	// extra_tags and metadata columns can vary between rows
+	csvReader.FieldsPerRecord = -1
+
+	header, err := csvReader.Read()
+	if err != nil {
+		return err
+	}
+
+	indices, err := BuildColumnIndicesK6Csv(header)
+	if err != nil {
+		return err
+	}
+
+	for {
+		rec, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		collector.add(TranslateK6CsvRow(rec, indices))
+	}
 
-	return rows, nil
+	return nil
 }
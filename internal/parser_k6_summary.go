@@ -0,0 +1,46 @@
+package internal
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+// ParseDataFileK6Summary reads the end-of-test summary written by k6 with
+// --summary-export, or the data passed to handleSummary. It only holds
+// totals, so the run is published without chart metrics.
+func ParseDataFileK6Summary(file string, options ParseOptions) (*ParsedData, error) {
+	if err := validateFile(file); err != nil {
+		return nil, err
+	}
+
+	contents, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read file %s", file)
+	}
+
+	var summary K6Summary
+	if err := json.Unmarshal(contents, &summary); err != nil {
+		return nil, errors.Wrapf(err, "cannot parse file %s", file)
+	}
+
+	if len(summary.Metrics) == 0 {
+		return nil, errors.Errorf("cannot parse file %s: no metrics found", file)
+	}
+
+	// the summary has no time stamps, only the duration of the run
+	if options.StartedAt == 0 {
+		return nil, errors.Errorf("cannot parse file %s: k6 summaries have no time stamps, pass the start of the run with --started-at", file)
+	}
+	duration := K6SummaryDuration(summary)
+	if duration == 0 {
+		return nil, errors.Errorf("cannot parse file %s: no test run duration or request rate found", file)
+	}
+
+	result := TranslateK6Summary(summary, options.LabelBy)
+	result.StartedAt = options.StartedAt
+	result.StoppedAt = options.StartedAt + uint64(duration.Milliseconds())
+
+	return &ParsedData{Summary: result}, nil
+}
@@ -11,12 +11,16 @@ import (
 	"github.com/pkg/errors"
 )
 
-func ParseDataFileLocust(file string) ([]UngroupedMetricDataPoint, error) {
+// ParseDataFileLocust reads the stats history written with --csv and
+// --csv-full-history. Rows hold running totals, so they are turned into the
+// requests of each interval. Aggregated rows hold the overall metrics.
+func ParseDataFileLocust(file string) (*ParsedData, error) {
 	span := sentry.StartSpan(context.Background(), "ParseDataFileLocust")
 	defer span.Finish()
 
 	var (
-		rows []UngroupedMetricDataPoint
+		buckets []AggregatedBucket
+		totals  = make(map[string]LocustTotals)
 	)
 
 	f, err := os.Open(file)
@@ -45,14 +49,19 @@ func ParseDataFileLocust(file string) ([]UngroupedMetricDataPoint, error) {
 			return nil, errors.Wrapf(err, "cannot read file %s", file)
 		}
 
-		if rec[indices["name"]] != "Aggregated" {
-			rows = append(rows, TranslateLocustRow(rec, indices))
+		key := rec[indices["Type"]] + " " + rec[indices["Name"]]
+		bucket, labelTotals := TranslateLocustRow(rec, indices, totals[key])
+		totals[key] = labelTotals
+
+		if bucket.Label == "Aggregated" {
+			bucket.Label = ""
 		}
+		buckets = append(buckets, bucket)
 	}
 
-	sort.SliceStable(rows, func(i int, j int) bool {
-		return rows[i].TimeStamp < rows[j].TimeStamp
+	sort.SliceStable(buckets, func(i int, j int) bool {
+		return buckets[i].TimeStamp < buckets[j].TimeStamp
 	})
 
-	return rows, nil
+	return &ParsedData{Buckets: buckets}, nil
 }
@@ -0,0 +1,174 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// ParseDataFileNBomber reads the CSV report of NBomber, or its node stats
+// serialized as JSON. A file can hold several reports of the same run, eg.
+// written at each reporting interval, which are published as the periods
+// between them. A single report is published as a summary.
+func ParseDataFileNBomber(file string, options ParseOptions) (*ParsedData, error) {
+	if err := validateFile(file); err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot open file %s", file)
+	}
+
+	contents, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read file %s", file)
+	}
+
+	var (
+		steps     []NBomberStepStats
+		startedAt uint64
+	)
+	if trimmed := bytes.TrimSpace(contents); bytes.HasPrefix(trimmed, []byte("{")) || bytes.HasPrefix(trimmed, []byte("[")) {
+		steps, startedAt, err = readNBomberJson(trimmed)
+	} else {
+		steps, err = readNBomberCsv(contents)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot parse file %s", file)
+	}
+
+	// the CSV report has no time stamps, so it is assumed to be written when
+	// the run stopped
+	if startedAt == 0 {
+		var duration uint64
+		for _, step := range steps {
+			if ms := uint64(step.Duration.Milliseconds()); ms > duration {
+				duration = ms
+			}
+		}
+		startedAt = uint64(info.ModTime().UnixMilli()) - duration
+	}
+
+	return translateNBomberSteps(steps, startedAt), nil
+}
+
+func translateNBomberSteps(steps []NBomberStepStats, startedAt uint64) *ParsedData {
+	sort.SliceStable(steps, func(i int, j int) bool {
+		return steps[i].Duration < steps[j].Duration
+	})
+
+	// scenario stats group the stats of their steps
+	hasSteps := make(map[string]bool)
+	for _, step := range steps {
+		if step.Step != "" {
+			hasSteps[step.Scenario] = true
+		}
+	}
+
+	var (
+		buckets []AggregatedBucket
+		totals  = make(map[string]NBomberTotals)
+		reports = make(map[string]int)
+		single  = true
+	)
+	for _, step := range steps {
+		label := NBomberLabel(step)
+		bucket, labelTotals := TranslateNBomberStats(step, startedAt, totals[label])
+		totals[label] = labelTotals
+		bucket.Transaction = step.Step == "" && hasSteps[step.Scenario]
+		buckets = append(buckets, bucket)
+
+		reports[label]++
+		single = single && reports[label] == 1
+	}
+
+	if !single {
+		return &ParsedData{Buckets: buckets}
+	}
+
+	summary, summaryByLabel := SummarizeAggregatedBuckets(buckets)
+	_, stoppedAt := AggregatedBucketsRange(buckets)
+	return &ParsedData{Summary: &SummaryData{
+		Summary:        summary,
+		SummaryByLabel: summaryByLabel,
+		StartedAt:      startedAt,
+		StoppedAt:      stoppedAt,
+	}}
+}
+
+func readNBomberCsv(contents []byte) ([]NBomberStepStats, error) {
+	csvReader := csv.NewReader(bytes.NewReader(contents))
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	indices, err := BuildColumnIndicesNBomber(header)
+	if err != nil {
+		return nil, err
+	}
+
+	var steps []NBomberStepStats
+	for {
+		rec, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		step, err := ParseNBomberCsvRow(rec, indices)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, step)
+	}
+
+	return steps, nil
+}
+
+// readNBomberJson reads node stats as a JSON object, an array or JSON lines.
+func readNBomberJson(contents []byte) ([]NBomberStepStats, uint64, error) {
+	var nodes []NBomberNodeStats
+	if bytes.HasPrefix(contents, []byte("[")) {
+		if err := json.Unmarshal(contents, &nodes); err != nil {
+			return nil, 0, err
+		}
+	} else {
+		decoder := json.NewDecoder(bytes.NewReader(contents))
+		for {
+			var node NBomberNodeStats
+			if err := decoder.Decode(&node); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, 0, err
+			}
+			nodes = append(nodes, node)
+		}
+	}
+
+	var (
+		steps     []NBomberStepStats
+		startedAt uint64
+	)
+	for _, node := range nodes {
+		nodeSteps, err := NBomberStepStatsFromNode(node)
+		if err != nil {
+			return nil, 0, err
+		}
+		steps = append(steps, nodeSteps...)
+
+		if created := node.TestInfo.Created; !created.IsZero() && (startedAt == 0 || uint64(created.UnixMilli()) < startedAt) {
+			startedAt = uint64(created.UnixMilli())
+		}
+	}
+
+	return steps, startedAt, nil
+}
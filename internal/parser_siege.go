@@ -0,0 +1,63 @@
+package internal
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// ParseDataFileSiege reads siege.log, written with siege --log. Siege appends
+// a line per run, so the last run is published.
+func ParseDataFileSiege(file string, options ParseOptions) (*ParsedData, error) {
+	if err := validateFile(file); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot open file %s", file)
+	}
+
+	defer f.Close()
+
+	csvReader := csv.NewReader(f)
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read file %s", file)
+	}
+
+	indices, err := BuildColumnIndicesSiege(header)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot parse file %s", file)
+	}
+
+	var last []string
+	for {
+		rec, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "cannot read file %s", file)
+		}
+		last = rec
+	}
+
+	if last == nil {
+		return nil, errors.Errorf("cannot parse file %s: no runs found", file)
+	}
+
+	summaryData, err := TranslateSiegeRow(last, indices)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot parse file %s", file)
+	}
+
+	// siege doesn't log URLs, so the run is labeled with --label
+	labelSummary := summaryData.Summary
+	labelSummary.Label = options.DefaultLabel
+	labelSummary.Throughput = nil
+	summaryData.SummaryByLabel = map[string]MetricSummary{options.DefaultLabel: labelSummary}
+
+	return &ParsedData{Summary: summaryData}, nil
+}
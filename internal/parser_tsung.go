@@ -0,0 +1,148 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/csv"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ParseDataFileTsung reads tsung.dump, which has a row per request, or the
+// interval stats of tsung.log.
+func ParseDataFileTsung(file string, options ParseOptions) (*ParsedData, error) {
+	if err := validateFile(file); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot open file %s", file)
+	}
+
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	head, err := reader.Peek(len("# stats:"))
+	if err != nil && err != io.EOF {
+		return nil, errors.Wrapf(err, "cannot read file %s", file)
+	}
+
+	if string(head) == "# stats:" {
+		if options.Samples {
+			return nil, errors.Errorf("cannot parse file %s: samples need tsung.dump", file)
+		}
+		return parseTsungLog(file, reader)
+	}
+
+	return parseTsungDump(file, reader, options)
+}
+
+func parseTsungDump(file string, reader *bufio.Reader, options ParseOptions) (*ParsedData, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.Comma = ';'
+	csvReader.FieldsPerRecord = -1
+	csvReader.LazyQuotes = true
+
+	var (
+		requests []TsungRequest
+		indices  = buildDefaultColumnIndicesTsungDump()
+		first    = true
+	)
+	for {
+		rec, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "cannot read file %s", file)
+		}
+
+		if first && strings.HasPrefix(rec[0], "#") {
+			indices, err = BuildColumnIndicesTsungDump(rec)
+			if err != nil {
+				return nil, errors.Wrapf(err, "cannot parse file %s", file)
+			}
+			first = false
+			continue
+		}
+		first = false
+
+		request, err := ParseTsungDumpRow(rec, indices)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot parse file %s", file)
+		}
+		requests = append(requests, request)
+	}
+
+	parsed := &ParsedData{}
+	if options.Samples {
+		for _, request := range requests {
+			parsed.Samples = append(parsed.Samples, TranslateTsungSample(request))
+		}
+		sort.SliceStable(parsed.Samples, func(i int, j int) bool {
+			return parsed.Samples[i].TimeStamp < parsed.Samples[j].TimeStamp
+		})
+		return parsed, nil
+	}
+
+	// each Tsung user is an Erlang process, active from its first request
+	// until its last response
+	type userSpan struct{ start, end uint64 }
+	spans := make(map[string]*userSpan)
+
+	var starts []uint64
+	for _, request := range requests {
+		row := TranslateTsungRequest(request)
+		parsed.Rows = append(parsed.Rows, row)
+		starts = append(starts, row.TimeStamp)
+
+		end := row.TimeStamp + row.Latency
+		if span, ok := spans[request.Pid]; !ok {
+			spans[request.Pid] = &userSpan{start: row.TimeStamp, end: end}
+		} else {
+			if row.TimeStamp < span.start {
+				span.start = row.TimeStamp
+			}
+			if end > span.end {
+				span.end = end
+			}
+		}
+	}
+
+	var events []UserEvent
+	for pid, span := range spans {
+		if pid == "" {
+			continue
+		}
+		events = append(events, UserEvent{TimeStamp: span.start, Started: true}, UserEvent{TimeStamp: span.end})
+	}
+	applyVirtualUsers(parsed.Rows, starts, VirtualUserSeriesFromEvents(events))
+
+	sort.SliceStable(parsed.Rows, func(i int, j int) bool {
+		return parsed.Rows[i].TimeStamp < parsed.Rows[j].TimeStamp
+	})
+
+	return parsed, nil
+}
+
+func parseTsungLog(file string, reader io.Reader) (*ParsedData, error) {
+	dumps, err := ParseTsungLog(reader)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot parse file %s", file)
+	}
+
+	parsed := &ParsedData{}
+	for i, dump := range dumps {
+		duration := tsungStatsInterval
+		if i > 0 && dump.TimeStamp > dumps[i-1].TimeStamp {
+			duration = time.Duration(dump.TimeStamp-dumps[i-1].TimeStamp) * time.Millisecond
+		}
+		parsed.Buckets = append(parsed.Buckets, TranslateTsungStats(dump, duration)...)
+	}
+
+	return parsed, nil
+}
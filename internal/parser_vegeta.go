@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/gob"
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// ParseDataFileVegeta reads vegeta results in any of its encodings: JSON lines
+// (vegeta encode --to json), CSV (--to csv) or the gob output of vegeta attack.
+func ParseDataFileVegeta(file string, options ParseOptions) (*ParsedData, error) {
+	if err := validateFile(file); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot open file %s", file)
+	}
+
+	defer f.Close()
+
+	results, err := readVegetaResults(bufio.NewReader(f))
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot parse file %s", file)
+	}
+
+	parsed := &ParsedData{}
+	if options.Samples {
+		for _, result := range results {
+			parsed.Samples = append(parsed.Samples, TranslateVegetaSample(result, options.LabelBy))
+		}
+		sort.SliceStable(parsed.Samples, func(i int, j int) bool {
+			return parsed.Samples[i].TimeStamp < parsed.Samples[j].TimeStamp
+		})
+		return parsed, nil
+	}
+
+	var starts []uint64
+	for _, result := range results {
+		row := TranslateVegetaRow(result, options.LabelBy)
+		parsed.Rows = append(parsed.Rows, row)
+		starts = append(starts, row.TimeStamp)
+	}
+
+	// vegeta doesn't report workers, so they are estimated from requests in flight
+	applyVirtualUsers(parsed.Rows, starts, &VirtualUserSeries{})
+
+	// results are written as requests complete
+	sort.SliceStable(parsed.Rows, func(i int, j int) bool {
+		return parsed.Rows[i].TimeStamp < parsed.Rows[j].TimeStamp
+	})
+
+	return parsed, nil
+}
+
+func readVegetaResults(reader *bufio.Reader) ([]VegetaResult, error) {
+	head, err := reader.Peek(1)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if len(head) == 0 {
+		return nil, nil
+	}
+
+	switch {
+	case head[0] == '{':
+		return readVegetaJson(reader)
+	case head[0] >= '0' && head[0] <= '9':
+		return readVegetaCsv(reader)
+	default:
+		return readVegetaGob(reader)
+	}
+}
+
+func readVegetaJson(reader io.Reader) ([]VegetaResult, error) {
+	var results []VegetaResult
+	decoder := json.NewDecoder(reader)
+	for {
+		var result VegetaResult
+		if err := decoder.Decode(&result); err == io.EOF {
+			return results, nil
+		} else if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+}
+
+func readVegetaCsv(reader io.Reader) ([]VegetaResult, error) {
+	var results []VegetaResult
+	csvReader := csv.NewReader(reader)
+	// older vegeta versions write fewer columns
+	csvReader.FieldsPerRecord = -1
+	for {
+		rec, err := csvReader.Read()
+		if err == io.EOF {
+			return results, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		result, err := ParseVegetaCsvRow(rec)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+}
+
+func readVegetaGob(reader io.Reader) ([]VegetaResult, error) {
+	var results []VegetaResult
+	decoder := gob.NewDecoder(reader)
+	for {
+		var result VegetaResult
+		if err := decoder.Decode(&result); err == io.EOF {
+			return results, nil
+		} else if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+}
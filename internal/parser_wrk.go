@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// ParseDataFileWrk reads the saved output of wrk or wrk2 run with --latency.
+// The output has no time stamps, so the run starts at options.StartedAt, or
+// the file is assumed to be written when the test stopped. The summary is
+// labeled with options.DefaultLabel, since the URL can hold ids and tokens.
+func ParseDataFileWrk(file string, options ParseOptions) (*ParsedData, error) {
+	if err := validateFile(file); err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot open file %s", file)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot open file %s", file)
+	}
+
+	defer f.Close()
+
+	result, err := ParseWrkOutput(f)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read file %s", file)
+	}
+
+	if result.Requests == 0 {
+		return nil, errors.Errorf("cannot parse file %s: no requests found", file)
+	}
+
+	label := options.DefaultLabel
+	if label == "" {
+		label = result.URL
+	}
+
+	summary := TranslateWrkResult(result)
+	labelSummary := summary
+	labelSummary.Label = label
+	labelSummary.Throughput = nil
+
+	stoppedAt := uint64(info.ModTime().UnixMilli())
+	startedAt := stoppedAt - uint64(result.Duration.Milliseconds())
+	if options.StartedAt > 0 {
+		startedAt = options.StartedAt
+		stoppedAt = startedAt + uint64(result.Duration.Milliseconds())
+	}
+
+	return &ParsedData{Summary: &SummaryData{
+		Summary:        summary,
+		SummaryByLabel: map[string]MetricSummary{label: labelSummary},
+		StartedAt:      startedAt,
+		StoppedAt:      stoppedAt,
+	}}, nil
+}
@@ -3,17 +3,29 @@ package internal
 import (
 	"context"
 	"log"
+	"time"
 
 	"github.com/getsentry/sentry-go"
 	"github.com/montanaflynn/stats"
 )
 
 type GlobalDataCounter struct {
-	Label           string
-	TotalRequests   uint64
-	TotalFailures   uint64
-	MaxVirtualUsers uint64
-	RawLatencies    []float64
+	Label              string
+	Transaction        bool
+	TotalRequests      uint64
+	TotalFailures      uint64
+	MaxVirtualUsers    uint64
+	RawLatencies       []float64
+	RawTimings         timingSamples
+	Apdex              apdexCounter
+	TotalBytesReceived uint64
+	TotalBytesSent     uint64
+	// bucket time stamps and peaks at the finest time aggregation level
+	FirstTimeStamp             uint64
+	LastTimeStamp              uint64
+	PeakRequestsPerSecond      float64
+	PeakBytesReceivedPerSecond float64
+	PeakBytesSentPerSecond     float64
 }
 
 type LabeledDataCounter = map[string]*GlobalDataCounter
@@ -21,11 +33,16 @@ type LabeledDataCounter = map[string]*GlobalDataCounter
 type GroupedResult struct {
 	DataPoints        []MetricDataPoint
 	DataPointsByLabel map[string][]MetricDataPoint
+	// only populated when rows come from more than one load generator
+	DataPointsByGenerator map[string][]MetricDataPoint
 }
 
 var globalDataCounter = &GlobalDataCounter{}
 var labeledDataCounter = make(map[string]*GlobalDataCounter)
 var globalCountersFull = false
+
+// allTimeAggregationLevels are the levels of the current run, finest first.
+// GroupAllDataPoints chooses them from the run duration.
 var allTimeAggregationLevels = []TimeAggregationLevel{
 	FiveSeconds,
 	ThirtySeconds,
@@ -34,20 +51,100 @@ var allTimeAggregationLevels = []TimeAggregationLevel{
 	ThirtyMinutes,
 }
 
+var candidateTimeAggregationLevels = []TimeAggregationLevel{
+	HalfSecond,
+	OneSecond,
+	FiveSeconds,
+	ThirtySeconds,
+	OneMinute,
+	FiveMinutes,
+	ThirtyMinutes,
+	OneHour,
+	SixHours,
+}
+
+// Bounds on the number of buckets per series for a level to be chosen.
+const (
+	maxTimeAggregationPoints = 1000
+	minTimeAggregationPoints = 6
+)
+
+// timeStampResolution detects results with whole second time stamps. Levels
+// finer than that would leave most buckets empty.
+func timeStampResolution(ungrouped []UngroupedMetricDataPoint) time.Duration {
+	for _, dp := range ungrouped {
+		if dp.TimeStamp%1000 != 0 {
+			return time.Millisecond
+		}
+	}
+	return time.Second
+}
+
+// ChooseTimeAggregationLevels picks the levels that give a useful number of
+// buckets for the run duration, eg. 1s buckets for a smoke test and 1h
+// buckets for a soak test. At least one level is always chosen.
+func ChooseTimeAggregationLevels(duration time.Duration, resolution time.Duration) []TimeAggregationLevel {
+	var (
+		levels    []TimeAggregationLevel
+		supported []TimeAggregationLevel
+	)
+
+	for _, level := range candidateTimeAggregationLevels {
+		if level.Duration() < resolution {
+			continue
+		}
+		supported = append(supported, level)
+
+		points := int64((duration + level.Duration() - 1) / level.Duration())
+		if points <= maxTimeAggregationPoints && points >= minTimeAggregationPoints {
+			levels = append(levels, level)
+		}
+	}
+
+	if len(levels) > 0 {
+		return levels
+	}
+
+	// results coarser than every level, eg. 12h periods, get the coarsest
+	if len(supported) == 0 {
+		return candidateTimeAggregationLevels[len(candidateTimeAggregationLevels)-1:]
+	}
+
+	// runs too short for several buckets get the finest level, runs too long
+	// for any level get the coarsest
+	if duration < supported[0].Duration()*minTimeAggregationPoints {
+		return supported[:1]
+	}
+	return supported[len(supported)-1:]
+}
+
 func GroupAllDataPoints(ungrouped []UngroupedMetricDataPoint) GroupedResult {
 	span := sentry.StartSpan(context.Background(), "GroupAllDataPoints")
 	defer span.Finish()
 
 	groupedResult := GroupedResult{}
 	groupedResult.DataPointsByLabel = make(map[string][]MetricDataPoint)
+	groupedResult.DataPointsByGenerator = make(map[string][]MetricDataPoint)
+
+	// start from empty totals, for programs that publish more than one run
+	globalDataCounter = &GlobalDataCounter{}
+	labeledDataCounter = make(map[string]*GlobalDataCounter)
+	globalCountersFull = false
+
+	if len(ungrouped) > 0 {
+		duration := time.Duration(ungrouped[len(ungrouped)-1].TimeStamp-ungrouped[0].TimeStamp) * time.Millisecond
+		allTimeAggregationLevels = ChooseTimeAggregationLevels(duration, timeStampResolution(ungrouped))
+	}
 
-	// TODO(bobsin): disqualify levels based on duration eg. duration / timeAggregationLevel < 1000
 	for _, timeAggregationLevel := range allTimeAggregationLevels {
 		localResult := GroupDataPoints(ungrouped, timeAggregationLevel)
 		groupedResult.DataPoints = append(groupedResult.DataPoints, localResult.DataPoints...)
 		for label, dataPoints := range localResult.DataPointsByLabel {
 			groupedResult.DataPointsByLabel[label] = append(groupedResult.DataPointsByLabel[label], dataPoints...)
 		}
+		for generator, dataPoints := range localResult.DataPointsByGenerator {
+			groupedResult.DataPointsByGenerator[generator] = append(groupedResult.DataPointsByGenerator[generator], dataPoints...)
+		}
 		globalCountersFull = true
 	}
 
@@ -56,60 +153,157 @@ func GroupAllDataPoints(ungrouped []UngroupedMetricDataPoint) GroupedResult {
 
 func GroupDataPoints(ungrouped []UngroupedMetricDataPoint, timeAggregationLevel TimeAggregationLevel) GroupedResult {
 	var (
-		startTime         uint64
-		dataPoints        []MetricDataPoint
-		dataPointsByLabel map[string][]MetricDataPoint
-		batch             []UngroupedMetricDataPoint
-		batchByLabel      map[string][]UngroupedMetricDataPoint
+		startTime             uint64
+		dataPoints            []MetricDataPoint
+		dataPointsByLabel     map[string][]MetricDataPoint
+		dataPointsByGenerator map[string][]MetricDataPoint
+		batch                 []UngroupedMetricDataPoint
+		batchByLabel          map[string][]UngroupedMetricDataPoint
+		batchByGenerator      map[string][]UngroupedMetricDataPoint
 	)
 	dataPointsByLabel = make(map[string][]MetricDataPoint)
+	dataPointsByGenerator = make(map[string][]MetricDataPoint)
 	batchByLabel = make(map[string][]UngroupedMetricDataPoint)
-
-	for _, dp := range ungrouped {
-		if startTime == 0 {
-			// init start time to last 5s interval from time stamp
-			startTime = calculateIntervalFloor(dp.TimeStamp, timeAggregationLevel.Seconds())
+	batchByGenerator = make(map[string][]UngroupedMetricDataPoint)
+	multipleGenerators := hasMultipleGenerators(ungrouped)
+	interval := timeAggregationLevel.Milliseconds()
+
+	for i, dp := range ungrouped {
+		// buckets are half-open, [startTime, startTime+interval)
+		bucket := calculateIntervalFloor(dp.TimeStamp, interval)
+		if i == 0 {
+			startTime = bucket
 		}
 
-		if dp.TimeStamp < startTime {
+		if bucket < startTime {
 			log.Printf("dp.TimeStamp: %d, startTime: %d", dp.TimeStamp, startTime)
 			log.Fatalf("received unsorted rows that break the reducer")
 		}
 
-		if dp.TimeStamp-startTime > timeAggregationLevel.Seconds() {
-			dataPoints = append(dataPoints, groupDataPointBatch(batch, startTime, "", timeAggregationLevel))
+		if bucket != startTime {
+			dataPoints = appendDataPoint(dataPoints, groupOverallBatch(batch, startTime, timeAggregationLevel))
 			mergeDataPointsByLabel(dataPointsByLabel, batchByLabel, startTime, timeAggregationLevel)
+			mergeDataPointsByGenerator(dataPointsByGenerator, batchByGenerator, startTime, timeAggregationLevel)
 			batch = nil
 			batchByLabel = map[string][]UngroupedMetricDataPoint{}
-			startTime = 0
+			batchByGenerator = map[string][]UngroupedMetricDataPoint{}
+			startTime = bucket
+		}
+
+		// unlabeled rows, eg. k6 data_received, only count towards overall metrics
+		if dp.Label != "" {
+			batchByLabel[dp.Label] = append(batchByLabel[dp.Label], dp)
+		}
+		if dp.Transaction {
+			// transactions group other requests, counting them overall would double count
+			continue
 		}
 
 		batch = append(batch, dp)
-		batchByLabel[dp.Label] = append(batchByLabel[dp.Label], dp)
+		if multipleGenerators {
+			batchByGenerator[dp.Generator] = append(batchByGenerator[dp.Generator], dp)
+		}
 	}
 
-	if len(batch) > 0 {
-		startTime = calculateIntervalFloor(batch[0].TimeStamp, timeAggregationLevel.Seconds())
-		dataPoints = append(dataPoints, groupDataPointBatch(batch, startTime, "", timeAggregationLevel))
+	if len(ungrouped) > 0 {
+		dataPoints = appendDataPoint(dataPoints, groupOverallBatch(batch, startTime, timeAggregationLevel))
 		mergeDataPointsByLabel(dataPointsByLabel, batchByLabel, startTime, timeAggregationLevel)
+		mergeDataPointsByGenerator(dataPointsByGenerator, batchByGenerator, startTime, timeAggregationLevel)
 	}
 
 	return GroupedResult{
-		DataPoints:        dataPoints,
-		DataPointsByLabel: dataPointsByLabel,
+		DataPoints:            dataPoints,
+		DataPointsByLabel:     dataPointsByLabel,
+		DataPointsByGenerator: dataPointsByGenerator,
+	}
+}
+
+// appendDataPoint adds zero request points for the buckets skipped since the
+// previous point, so charts show gaps instead of interpolating.
+func appendDataPoint(existing []MetricDataPoint, dp MetricDataPoint) []MetricDataPoint {
+	interval := dp.TimeAggregationLevel.Milliseconds()
+	if len(existing) > 0 {
+		for timeStamp := existing[len(existing)-1].TimeStamp + interval; timeStamp < dp.TimeStamp; timeStamp += interval {
+			empty := emptyDataPoint(timeStamp, dp.TimeAggregationLevel)
+			empty.Label = dp.Label
+			empty.Generator = dp.Generator
+			empty.Transaction = dp.Transaction
+			existing = append(existing, empty)
+		}
+	}
+
+	return append(existing, dp)
+}
+
+func emptyDataPoint(timeStamp uint64, timeAggregationLevel TimeAggregationLevel) MetricDataPoint {
+	return MetricDataPoint{
+		TimeStamp:            timeStamp,
+		TimeAggregationLevel: timeAggregationLevel,
+		Latencies:            &Latencies{},
+		Throughput:           &Throughput{},
+	}
+}
+
+func hasMultipleGenerators(ungrouped []UngroupedMetricDataPoint) bool {
+	for _, dp := range ungrouped {
+		if dp.Generator != ungrouped[0].Generator {
+			return true
+		}
 	}
+	return false
+}
+
+func groupOverallBatch(ungrouped []UngroupedMetricDataPoint, startTime uint64, timeAggregationLevel TimeAggregationLevel) MetricDataPoint {
+	// buckets with only transactions have no overall requests
+	if len(ungrouped) == 0 {
+		return emptyDataPoint(startTime, timeAggregationLevel)
+	}
+
+	grouped := groupDataPointBatch(ungrouped, startTime, "", timeAggregationLevel)
+
+	// requests come from labeled batches, but bandwidth can be reported
+	// without a label and peaks need the overall buckets
+	var bytesReceived, bytesSent uint64
+	for _, dp := range ungrouped {
+		bytesReceived += dp.BytesReceived
+		bytesSent += dp.BytesSent
+	}
+	updateThroughputCounter(globalDataCounter, grouped, bytesReceived, bytesSent)
+
+	return grouped
 }
 
 func mergeDataPointsByLabel(existing map[string][]MetricDataPoint, batch map[string][]UngroupedMetricDataPoint, startTime uint64, timeAggregationLevel TimeAggregationLevel) {
 	for label, dataPoints := range batch {
-		existing[label] = append(existing[label], groupDataPointBatch(dataPoints, startTime, label, timeAggregationLevel))
+		existing[label] = appendDataPoint(existing[label], groupDataPointBatch(dataPoints, startTime, label, timeAggregationLevel))
+	}
+}
+
+func mergeDataPointsByGenerator(existing map[string][]MetricDataPoint, batch map[string][]UngroupedMetricDataPoint, startTime uint64, timeAggregationLevel TimeAggregationLevel) {
+	for generator, dataPoints := range batch {
+		// generator breakdowns are not labeled so they stay out of the summary counters
+		grouped := groupDataPointBatch(dataPoints, startTime, "", timeAggregationLevel)
+		grouped.Generator = generator
+		existing[generator] = appendDataPoint(existing[generator], grouped)
 	}
 }
 
 func groupDataPointBatch(ungrouped []UngroupedMetricDataPoint, startTime uint64, label string, timeAggregationLevel TimeAggregationLevel) MetricDataPoint {
 	var (
-		latencies []float64
-		grouped   MetricDataPoint
+		latencies                []float64
+		timings                  timingSamples
+		apdex                    apdexCounter
+		grouped                  MetricDataPoint
+		bytesReceived            uint64
+		bytesSent                uint64
+		virtualUsersPerGenerator = make(map[string]uint64)
+		// rows that aren't transactions, the only ones counted overall
+		requests            MetricDataPoint
+		requestLatencies    []float64
+		requestTimings      timingSamples
+		requestApdex        apdexCounter
+		requestVirtualUsers = make(map[string]uint64)
+		transactionsOnly    = len(ungrouped) > 0
 	)
 
 	grouped.TimeStamp = startTime
@@ -122,29 +316,115 @@ func groupDataPointBatch(ungrouped []UngroupedMetricDataPoint, startTime uint64,
 
 		grouped.Requests += dp.Requests
 		grouped.Failures += dp.Failures
+		bytesReceived += dp.BytesReceived
+		bytesSent += dp.BytesSent
 
-		if dp.VirtualUsers > grouped.VirtualUsers {
-			grouped.VirtualUsers = dp.VirtualUsers
+		if dp.VirtualUsers > virtualUsersPerGenerator[dp.Generator] {
+			virtualUsersPerGenerator[dp.Generator] = dp.VirtualUsers
 		}
 
-		latencies = append(latencies, float64(dp.Latency))
+		if dp.Requests > 0 {
+			latencies = append(latencies, float64(dp.Latency))
+			timings.add(dp)
+			apdex.add(dp)
+		}
+
+		if dp.Transaction {
+			continue
+		}
+		// a label can name both a transaction and a request, eg. a Gatling group
+		// and a request inside it
+		transactionsOnly = false
+		requests.Requests += dp.Requests
+		requests.Failures += dp.Failures
+		if dp.VirtualUsers > requestVirtualUsers[dp.Generator] {
+			requestVirtualUsers[dp.Generator] = dp.VirtualUsers
+		}
+		if dp.Requests > 0 {
+			requestLatencies = append(requestLatencies, float64(dp.Latency))
+			requestTimings.add(dp)
+			requestApdex.add(dp)
+		}
+	}
+
+	// each generator reports its own thread count, so the bucket total is the sum
+	for _, virtualUsers := range virtualUsersPerGenerator {
+		grouped.VirtualUsers += virtualUsers
+	}
+
+	for _, virtualUsers := range requestVirtualUsers {
+		requests.VirtualUsers += virtualUsers
 	}
 
 	grouped.Latencies = calculateLatencySummary(latencies)
+	grouped.LatencyBreakdown = timings.summary()
+	grouped.Apdex = apdex.summary()
+	grouped.Throughput = calculateThroughput(grouped.Requests, bytesReceived, bytesSent, timeAggregationLevel.Duration())
 
 	if label != "" {
-		updateGlobalCounter(globalDataCounter, grouped, latencies)
+		if !transactionsOnly {
+			updateGlobalCounter(globalDataCounter, requests, requestLatencies, requestTimings, requestApdex)
+		}
 		if labeledDataCounter[label] == nil {
-			labeledDataCounter[label] = &GlobalDataCounter{}
+			labeledDataCounter[label] = &GlobalDataCounter{Transaction: transactionsOnly}
 		}
+		// the label is a transaction only if all of its rows are
+		labeledDataCounter[label].Transaction = labeledDataCounter[label].Transaction && transactionsOnly
+		grouped.Transaction = labeledDataCounter[label].Transaction
 
-		updateGlobalCounter(labeledDataCounter[label], grouped, latencies)
+		updateGlobalCounter(labeledDataCounter[label], grouped, latencies, timings, apdex)
+		updateThroughputCounter(labeledDataCounter[label], grouped, bytesReceived, bytesSent)
 	}
 
 	return grouped
 }
 
-func updateGlobalCounter(counter *GlobalDataCounter, metric MetricDataPoint, latencies []float64) {
+func calculateThroughput(requests uint64, bytesReceived uint64, bytesSent uint64, duration time.Duration) *Throughput {
+	seconds := duration.Seconds()
+	throughput := Throughput{
+		RequestsPerSecond:      float64(requests) / seconds,
+		BytesReceivedPerSecond: float64(bytesReceived) / seconds,
+		BytesSentPerSecond:     float64(bytesSent) / seconds,
+	}
+
+	if requests > 0 {
+		throughput.AvgResponseBytes = float64(bytesReceived) / float64(requests)
+	}
+
+	throughput.RequestsPerSecond, _ = stats.Round(throughput.RequestsPerSecond, 2)
+	throughput.BytesReceivedPerSecond, _ = stats.Round(throughput.BytesReceivedPerSecond, 2)
+	throughput.BytesSentPerSecond, _ = stats.Round(throughput.BytesSentPerSecond, 2)
+	throughput.AvgResponseBytes, _ = stats.Round(throughput.AvgResponseBytes, 2)
+
+	return &throughput
+}
+
+func updateThroughputCounter(counter *GlobalDataCounter, metric MetricDataPoint, bytesReceived uint64, bytesSent uint64) {
+	if globalCountersFull {
+		return
+	}
+
+	counter.TotalBytesReceived += bytesReceived
+	counter.TotalBytesSent += bytesSent
+	if counter.FirstTimeStamp == 0 || metric.TimeStamp < counter.FirstTimeStamp {
+		counter.FirstTimeStamp = metric.TimeStamp
+	}
+	if metric.TimeStamp > counter.LastTimeStamp {
+		counter.LastTimeStamp = metric.TimeStamp
+	}
+
+	if metric.Throughput.RequestsPerSecond > counter.PeakRequestsPerSecond {
+		counter.PeakRequestsPerSecond = metric.Throughput.RequestsPerSecond
+	}
+	if metric.Throughput.BytesReceivedPerSecond > counter.PeakBytesReceivedPerSecond {
+		counter.PeakBytesReceivedPerSecond = metric.Throughput.BytesReceivedPerSecond
+	}
+	if metric.Throughput.BytesSentPerSecond > counter.PeakBytesSentPerSecond {
+		counter.PeakBytesSentPerSecond = metric.Throughput.BytesSentPerSecond
+	}
+}
+
+func updateGlobalCounter(counter *GlobalDataCounter, metric MetricDataPoint, latencies []float64, timings timingSamples, apdex apdexCounter) {
 	if globalCountersFull {
 		return
 	}
@@ -155,6 +435,43 @@ func updateGlobalCounter(counter *GlobalDataCounter, metric MetricDataPoint, lat
 		counter.MaxVirtualUsers = metric.VirtualUsers
 	}
 	counter.RawLatencies = append(counter.RawLatencies, latencies...)
+	counter.RawTimings.merge(timings)
+	counter.Apdex.merge(apdex)
+}
+
+// timingSamples holds the latency breakdown of requests that report one.
+type timingSamples struct {
+	connect []float64
+	server  []float64
+	idle    []float64
+}
+
+func (t *timingSamples) add(dp UngroupedMetricDataPoint) {
+	if !dp.HasTimings {
+		return
+	}
+
+	t.connect = append(t.connect, float64(dp.ConnectTime))
+	t.server = append(t.server, float64(dp.ServerLatency))
+	t.idle = append(t.idle, float64(dp.IdleTime))
+}
+
+func (t *timingSamples) merge(other timingSamples) {
+	t.connect = append(t.connect, other.connect...)
+	t.server = append(t.server, other.server...)
+	t.idle = append(t.idle, other.idle...)
+}
+
+func (t *timingSamples) summary() *LatencyBreakdown {
+	if len(t.server) == 0 {
+		return nil
+	}
+
+	return &LatencyBreakdown{
+		Connect: calculateLatencySummary(t.connect),
+		Server:  calculateLatencySummary(t.server),
+		Idle:    calculateLatencySummary(t.idle),
+	}
 }
 
 func calculateLatencySummary(latencies []float64) *Latencies {
@@ -168,6 +485,12 @@ func calculateLatencySummary(latencies []float64) *Latencies {
 	summary.P95Ms, _ = stats.Percentile(latencies, 95)
 	summary.P99Ms, _ = stats.Percentile(latencies, 99)
 
+	roundLatencies(&summary)
+
+	return &summary
+}
+
+func roundLatencies(summary *Latencies) {
 	summary.AvgMs, _ = stats.Round(summary.AvgMs, 2)
 	summary.MaxMs, _ = stats.Round(summary.MaxMs, 2)
 	summary.MinMs, _ = stats.Round(summary.MinMs, 2)
@@ -176,8 +499,6 @@ func calculateLatencySummary(latencies []float64) *Latencies {
 	summary.P90Ms, _ = stats.Round(summary.P90Ms, 2)
 	summary.P95Ms, _ = stats.Round(summary.P95Ms, 2)
 	summary.P99Ms, _ = stats.Round(summary.P99Ms, 2)
-
-	return &summary
 }
 
 func CalculateMetricSummaryOverall() MetricSummary {
@@ -200,15 +521,41 @@ func calculateMetricSummary(globalDataCounter *GlobalDataCounter, label string)
 		summary.Label = label
 	}
 
+	summary.Transaction = globalDataCounter.Transaction
 	summary.TotalRequests = globalDataCounter.TotalRequests
 	summary.TotalFailures = globalDataCounter.TotalFailures
 	summary.MaxVirtualUsers = globalDataCounter.MaxVirtualUsers
 	summary.Latencies = calculateLatencySummary(globalDataCounter.RawLatencies)
+	summary.LatencyBreakdown = globalDataCounter.RawTimings.summary()
+	summary.Apdex = globalDataCounter.Apdex.summary()
+	summary.Throughput = calculateThroughputSummary(globalDataCounter)
 
 	return summary
 }
 
-func calculateIntervalFloor(timeStamp uint64, timeAggSeconds uint64) uint64 {
-	difference := timeStamp % timeAggSeconds
+func calculateThroughputSummary(counter *GlobalDataCounter) *ThroughputSummary {
+	summary := ThroughputSummary{
+		PeakRequestsPerSecond:      counter.PeakRequestsPerSecond,
+		PeakBytesReceivedPerSecond: counter.PeakBytesReceivedPerSecond,
+		PeakBytesSentPerSecond:     counter.PeakBytesSentPerSecond,
+		TotalBytesReceived:         counter.TotalBytesReceived,
+		TotalBytesSent:             counter.TotalBytesSent,
+	}
+
+	if counter.LastTimeStamp >= counter.FirstTimeStamp && counter.FirstTimeStamp > 0 {
+		// the last bucket covers a full interval at the finest level
+		duration := time.Duration(counter.LastTimeStamp-counter.FirstTimeStamp)*time.Millisecond + allTimeAggregationLevels[0].Duration()
+		mean := calculateThroughput(counter.TotalRequests, counter.TotalBytesReceived, counter.TotalBytesSent, duration)
+		summary.MeanRequestsPerSecond = mean.RequestsPerSecond
+		summary.MeanBytesReceivedPerSecond = mean.BytesReceivedPerSecond
+		summary.MeanBytesSentPerSecond = mean.BytesSentPerSecond
+		summary.AvgResponseBytes = mean.AvgResponseBytes
+	}
+
+	return &summary
+}
+
+func calculateIntervalFloor(timeStamp uint64, interval uint64) uint64 {
+	difference := timeStamp % interval
 	return timeStamp - difference
 }
@@ -0,0 +1,205 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGroupDataPointsSumsVirtualUsersAcrossGenerators(t *testing.T) {
+	rows := MergeDataPoints([][]UngroupedMetricDataPoint{
+		{
+			{TimeStamp: 1000000, Requests: 1, VirtualUsers: 5, Label: "a", Generator: "a.jtl"},
+			{TimeStamp: 1002000, Requests: 1, VirtualUsers: 10, Label: "a", Generator: "a.jtl"},
+		},
+		{
+			{TimeStamp: 1001000, Requests: 1, VirtualUsers: 7, Label: "a", Generator: "b.jtl"},
+		},
+	})
+
+	for i := 1; i < len(rows); i++ {
+		if rows[i].TimeStamp < rows[i-1].TimeStamp {
+			t.Error("Failed to merge rows in time order: ", rows[i-1].TimeStamp, " before ", rows[i].TimeStamp)
+		}
+	}
+
+	result := GroupDataPoints(rows, FiveSeconds)
+	if len(result.DataPoints) != 1 {
+		t.Fatal("Failed to group data points: ", len(result.DataPoints), " expected: ", 1)
+	}
+
+	if result.DataPoints[0].VirtualUsers != 17 {
+		t.Error("Failed to sum virtual users: ", result.DataPoints[0].VirtualUsers, " expected: ", 17)
+	}
+
+	if len(result.DataPointsByGenerator) != 2 {
+		t.Error("Failed to group by generator: ", len(result.DataPointsByGenerator), " expected: ", 2)
+	}
+}
+
+func TestEstimateConcurrency(t *testing.T) {
+	starts := []uint64{0, 10, 20, 100, 105}
+	latencies := []uint64{50, 50, 5, 10, 10}
+
+	expected := []uint64{1, 2, 3, 1, 2}
+	for i, inFlight := range estimateConcurrency(starts, latencies) {
+		if inFlight != expected[i] {
+			t.Error("Failed to estimate concurrency: ", inFlight, " expected: ", expected[i], " at index: ", i)
+		}
+	}
+}
+
+func TestGroupDataPointsThroughput(t *testing.T) {
+	rows := []UngroupedMetricDataPoint{
+		{TimeStamp: 1000000, Requests: 1, Latency: 100, Label: "a", BytesReceived: 400, BytesSent: 100},
+		{TimeStamp: 1001000, Requests: 1, Latency: 300, Label: "a", BytesReceived: 600, BytesSent: 100},
+		// bandwidth reported separately from requests, eg. k6 data_received
+		{TimeStamp: 1002000, BytesReceived: 1500},
+	}
+
+	result := GroupDataPoints(rows, FiveSeconds)
+	if len(result.DataPoints) != 1 {
+		t.Fatal("Failed to group data points: ", len(result.DataPoints), " expected: ", 1)
+	}
+
+	throughput := result.DataPoints[0].Throughput
+	if throughput.RequestsPerSecond != 0.4 {
+		t.Error("Failed to calculate requests per second: ", throughput.RequestsPerSecond, " expected: ", 0.4)
+	}
+	if throughput.BytesReceivedPerSecond != 500 {
+		t.Error("Failed to calculate received bytes per second: ", throughput.BytesReceivedPerSecond, " expected: ", 500)
+	}
+	if throughput.AvgResponseBytes != 1250 {
+		t.Error("Failed to calculate average response size: ", throughput.AvgResponseBytes, " expected: ", 1250)
+	}
+
+	if result.DataPoints[0].Latencies.MinMs != 100 {
+		t.Error("Failed to skip bandwidth rows in latencies: ", result.DataPoints[0].Latencies.MinMs, " expected: ", 100)
+	}
+
+	if _, ok := result.DataPointsByLabel[""]; ok {
+		t.Error("Failed to skip unlabeled rows in label breakdown")
+	}
+}
+
+func TestChooseTimeAggregationLevels(t *testing.T) {
+	tests := []struct {
+		duration time.Duration
+		expected []TimeAggregationLevel
+	}{
+		{20 * time.Second, []TimeAggregationLevel{OneSecond}},
+		{2 * time.Second, []TimeAggregationLevel{OneSecond}},
+		{30 * time.Minute, []TimeAggregationLevel{FiveSeconds, ThirtySeconds, OneMinute, FiveMinutes}},
+		{72 * time.Hour, []TimeAggregationLevel{FiveMinutes, ThirtyMinutes, OneHour, SixHours}},
+	}
+
+	for _, test := range tests {
+		levels := ChooseTimeAggregationLevels(test.duration, time.Second)
+		if !reflect.DeepEqual(levels, test.expected) {
+			t.Error("Failed to choose levels for ", test.duration, ": ", levels, " expected: ", test.expected)
+		}
+	}
+
+	levels := ChooseTimeAggregationLevels(20*time.Second, time.Millisecond)
+	if levels[0] != HalfSecond {
+		t.Error("Failed to choose sub-second level: ", levels[0], " expected: ", HalfSecond)
+	}
+
+	// pre-aggregated periods can be coarser than every level
+	levels = ChooseTimeAggregationLevels(24*time.Hour, 12*time.Hour)
+	if !reflect.DeepEqual(levels, []TimeAggregationLevel{SixHours}) {
+		t.Error("Failed to fall back to the coarsest level: ", levels, " expected: ", []TimeAggregationLevel{SixHours})
+	}
+}
+
+func TestGroupDataPointsBucketBoundaries(t *testing.T) {
+	rows := []UngroupedMetricDataPoint{
+		{TimeStamp: 1000000, Requests: 1, Label: "a"},
+		{TimeStamp: 1004999, Requests: 1, Label: "a"},
+		// buckets are half-open, so this starts the next bucket
+		{TimeStamp: 1005000, Requests: 1, Label: "a"},
+		// nothing happens for 10s
+		{TimeStamp: 1021000, Requests: 1, Label: "a"},
+	}
+
+	result := GroupDataPoints(rows, FiveSeconds)
+
+	expected := []struct {
+		timeStamp uint64
+		requests  uint64
+	}{
+		{1000000, 2},
+		{1005000, 1},
+		{1010000, 0},
+		{1015000, 0},
+		{1020000, 1},
+	}
+
+	if len(result.DataPoints) != len(expected) {
+		t.Fatal("Failed to group data points: ", len(result.DataPoints), " expected: ", len(expected))
+	}
+
+	for i, dp := range result.DataPoints {
+		if dp.TimeStamp != expected[i].timeStamp || dp.Requests != expected[i].requests {
+			t.Error("Failed to group bucket: ", dp.TimeStamp, dp.Requests, " expected: ", expected[i].timeStamp, expected[i].requests)
+		}
+	}
+
+	if len(result.DataPointsByLabel["a"]) != len(expected) {
+		t.Error("Failed to fill label gaps: ", len(result.DataPointsByLabel["a"]), " expected: ", len(expected))
+	}
+}
+
+func TestGroupAllDataPointsLabelSharedByTransactionAndRequest(t *testing.T) {
+	// a Gatling group and a request inside it can have the same name
+	rows := []UngroupedMetricDataPoint{
+		{TimeStamp: 1000000, Requests: 1, Latency: 300, Label: "checkout", Transaction: true},
+		{TimeStamp: 1000100, Requests: 1, Latency: 100, Label: "checkout"},
+		{TimeStamp: 1000200, Requests: 1, Latency: 200, Label: "payment"},
+	}
+
+	GroupAllDataPoints(rows)
+
+	overall := CalculateMetricSummaryOverall()
+	if overall.TotalRequests != 2 {
+		t.Error("Failed to skip transaction rows overall: ", overall.TotalRequests, " expected: ", 2)
+	}
+	if overall.Latencies.MaxMs != 200 {
+		t.Error("Failed to skip transaction latencies overall: ", overall.Latencies.MaxMs, " expected: ", 200)
+	}
+
+	checkout := CalculateMetricSummaryByLabel()["checkout"]
+	if checkout.Transaction {
+		t.Error("Failed to keep a label with requests as a non-transaction")
+	}
+	if checkout.TotalRequests != 2 {
+		t.Error("Failed to count label requests: ", checkout.TotalRequests, " expected: ", 2)
+	}
+}
+
+func TestCalculateMetricSummaryOverallSumsVirtualUsersAcrossGenerators(t *testing.T) {
+	var streams [][]UngroupedMetricDataPoint
+	for _, generator := range []string{"a.jtl", "b.jtl"} {
+		var rows []UngroupedMetricDataPoint
+		for i := 0; i < 600; i++ {
+			rows = append(rows, UngroupedMetricDataPoint{
+				TimeStamp:    uint64(1000000 + i*1000),
+				Requests:     1,
+				VirtualUsers: 10,
+				Label:        "checkout",
+				Generator:    generator,
+			})
+		}
+		streams = append(streams, rows)
+	}
+
+	GroupAllDataPoints(MergeDataPoints(streams))
+
+	if overall := CalculateMetricSummaryOverall(); overall.MaxVirtualUsers != 20 {
+		t.Error("Failed to sum virtual users overall: ", overall.MaxVirtualUsers, " expected: ", 20)
+	}
+
+	if labeled := CalculateMetricSummaryByLabel()["checkout"]; labeled.MaxVirtualUsers != 20 {
+		t.Error("Failed to sum labeled virtual users: ", labeled.MaxVirtualUsers, " expected: ", 20)
+	}
+}
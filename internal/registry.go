@@ -0,0 +1,534 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Parser reads a results format into a sink. Parsers should add rows and
+// samples as they read them, so a sink that reduces as it goes, eg. in a
+// program embedding the CLI, doesn't hold every row. The built-in JMeter parser
+// streams, formats whose virtual users or totals are worked out over the
+// whole file add what they read once it is read. ParsedData, the sink of the
+// publish command, keeps everything.
+type Parser interface {
+	// Name is the value of --format, eg. "jmeter".
+	Name() string
+	// Description is listed in --format help text.
+	Description() string
+	// Detect reports whether a file is in this format, from its name and the
+	// first bytes of its contents.
+	Detect(file string, head []byte) bool
+	// Parse reads the file into the sink. When options.Samples is set,
+	// samples should be added instead of rows.
+	Parse(file string, options ParseOptions, sink Sink) error
+}
+
+// Sink receives parsed data. ParsedData is a sink that keeps everything.
+type Sink interface {
+	AddRow(row UngroupedMetricDataPoint)
+	AddSample(sample LingoSample)
+	AddCustomMetric(point CustomMetricPoint)
+	AddError(event ErrorEvent)
+	SetMetadata(metadata *RunMetadata)
+	SetSummary(summary *SummaryData)
+}
+
+// BucketSink is implemented by sinks that accept requests aggregated by the
+// tool, for formats that only report totals per period. Parsers of such
+// formats check for it with a type assertion, so sinks written before it
+// existed keep working with every other format.
+type BucketSink interface {
+	AddBucket(bucket AggregatedBucket)
+}
+
+// FormatAuto detects the format of each file from the registered parsers.
+const FormatAuto = "auto"
+
+// detectHeadSize is how much of a file is read to detect its format.
+const detectHeadSize = 4096
+
+var (
+	parsersMu sync.RWMutex
+	parsers   []Parser
+)
+
+func init() {
+	for _, parser := range []Parser{
+		jmeterParser{},
+		k6Parser{},
+		k6SummaryParser{},
+		gatlingParser{},
+		locustParser{},
+		vegetaParser{},
+		artilleryParser{},
+		wrkParser{},
+		heyParser{},
+		abParser{},
+		siegeParser{},
+		ghzParser{},
+		fortioParser{},
+		nbomberParser{},
+		tsungParser{},
+	} {
+		RegisterParser(parser)
+	}
+}
+
+// RegisterParser makes a format available to --format. Like database/sql
+// drivers, registering the same name twice panics.
+func RegisterParser(parser Parser) {
+	parsersMu.Lock()
+	defer parsersMu.Unlock()
+
+	if parser == nil {
+		panic("parser: RegisterParser parser is nil")
+	}
+	for _, registered := range parsers {
+		if registered.Name() == parser.Name() {
+			panic("parser: RegisterParser called twice for format " + parser.Name())
+		}
+	}
+
+	parsers = append(parsers, parser)
+}
+
+func LookupParser(name string) (Parser, bool) {
+	parsersMu.RLock()
+	defer parsersMu.RUnlock()
+
+	for _, parser := range parsers {
+		if parser.Name() == name {
+			return parser, true
+		}
+	}
+	return nil, false
+}
+
+// Parsers returns the registered parsers sorted by name.
+func Parsers() []Parser {
+	parsersMu.RLock()
+	defer parsersMu.RUnlock()
+
+	sorted := make([]Parser, len(parsers))
+	copy(sorted, parsers)
+	sort.SliceStable(sorted, func(i int, j int) bool {
+		return sorted[i].Name() < sorted[j].Name()
+	})
+
+	return sorted
+}
+
+func ParserNames() []string {
+	var names []string
+	for _, parser := range Parsers() {
+		names = append(names, parser.Name())
+	}
+	return names
+}
+
+// DetectParser asks parsers in registration order whether they can read the
+// file, so built-in formats are tried before formats registered later.
+func DetectParser(file string) (Parser, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot open file %s", file)
+	}
+	defer f.Close()
+
+	head := make([]byte, detectHeadSize)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, errors.Wrapf(err, "cannot read file %s", file)
+	}
+	head = head[:n]
+
+	parsersMu.RLock()
+	defer parsersMu.RUnlock()
+
+	for _, parser := range parsers {
+		if parser.Detect(file, head) {
+			return parser, nil
+		}
+	}
+
+	return nil, fmt.Errorf("cannot detect the format of %s, please set --format", file)
+}
+
+type jmeterParser struct{}
+
+func (jmeterParser) Name() string        { return "jmeter" }
+func (jmeterParser) Description() string { return "JMeter CSV or XML results" }
+
+func (jmeterParser) Detect(file string, head []byte) bool {
+	if bytes.HasPrefix(head, []byte("<")) {
+		return bytes.Contains(head, []byte("<testResults"))
+	}
+	header := firstLine(head)
+	return strings.Contains(header, "timeStamp") && strings.Contains(header, "elapsed")
+}
+
+func (jmeterParser) Parse(file string, options ParseOptions, sink Sink) error {
+	if options.Samples {
+		return ParseDataFileSamples(file, sink)
+	}
+	return ParseDataFileJmeter(file, sink)
+}
+
+type k6Parser struct{}
+
+func (k6Parser) Name() string        { return "k6" }
+func (k6Parser) Description() string { return "k6 JSON or CSV output" }
+
+func (k6Parser) Detect(file string, head []byte) bool {
+	if bytes.HasPrefix(head, []byte("{")) {
+		return bytes.Contains(firstLineBytes(head), []byte(`"type":"Metric"`)) || bytes.Contains(firstLineBytes(head), []byte(`"type":"Point"`))
+	}
+	return strings.HasPrefix(firstLine(head), "metric_name,timestamp")
+}
+
+func (k6Parser) Parse(file string, options ParseOptions, sink Sink) error {
+	if options.Samples {
+		return errSamplesNotSupported("k6")
+	}
+
+	parsed, err := ParseDataFileK6(file, options)
+	if err != nil {
+		return err
+	}
+	return addParsedData(sink, parsed)
+}
+
+type k6SummaryParser struct{}
+
+func (k6SummaryParser) Name() string        { return "k6-summary" }
+func (k6SummaryParser) Description() string { return "k6 end-of-test summary export" }
+
+func (k6SummaryParser) Detect(file string, head []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(head), []byte("{")) && bytes.Contains(head, []byte(`"metrics"`))
+}
+
+func (k6SummaryParser) Parse(file string, options ParseOptions, sink Sink) error {
+	if options.Samples {
+		return errSamplesNotSupported("k6-summary")
+	}
+
+	parsed, err := ParseDataFileK6Summary(file, options)
+	if err != nil {
+		return err
+	}
+	return addParsedData(sink, parsed)
+}
+
+type gatlingParser struct{}
+
+func (gatlingParser) Name() string        { return "gatling" }
+func (gatlingParser) Description() string { return "Gatling simulation.log" }
+
+func (gatlingParser) Detect(file string, head []byte) bool {
+	if filepath.Base(file) == "simulation.log" {
+		return true
+	}
+	line := firstLine(head)
+	return strings.HasPrefix(line, "RUN\t") || strings.HasPrefix(line, "ASSERTION\t")
+}
+
+func (gatlingParser) Parse(file string, options ParseOptions, sink Sink) error {
+	if options.Samples {
+		return errSamplesNotSupported("gatling")
+	}
+
+	parsed, err := ParseDataFileGatling(file)
+	if err != nil {
+		return err
+	}
+	return addParsedData(sink, parsed)
+}
+
+type locustParser struct{}
+
+func (locustParser) Name() string        { return "locust" }
+func (locustParser) Description() string { return "Locust full stats history CSV" }
+
+func (locustParser) Detect(file string, head []byte) bool {
+	return strings.HasPrefix(firstLine(head), "Timestamp,User Count")
+}
+
+func (locustParser) Parse(file string, options ParseOptions, sink Sink) error {
+	if options.Samples {
+		return errSamplesNotSupported("locust")
+	}
+
+	parsed, err := ParseDataFileLocust(file)
+	if err != nil {
+		return err
+	}
+	return addParsedData(sink, parsed)
+}
+
+type vegetaParser struct{}
+
+func (vegetaParser) Name() string        { return "vegeta" }
+func (vegetaParser) Description() string { return "vegeta JSON, CSV or gob results" }
+
+func (vegetaParser) Detect(file string, head []byte) bool {
+	line := firstLineBytes(head)
+	if bytes.HasPrefix(line, []byte("{")) {
+		return bytes.Contains(line, []byte(`"bytes_in"`)) && bytes.Contains(line, []byte(`"latency"`))
+	}
+	// gob streams start with the type definition, which names the fields
+	if bytes.Contains(head, []byte("Timestamp")) && bytes.Contains(head, []byte("BytesIn")) {
+		return true
+	}
+	// CSV rows start with a nanosecond time stamp and have no header
+	fields := strings.Split(string(line), ",")
+	_, err := strconv.ParseUint(fields[0], 10, 64)
+	return err == nil && len(fields[0]) == 19 && len(fields) >= 6
+}
+
+func (vegetaParser) Parse(file string, options ParseOptions, sink Sink) error {
+	parsed, err := ParseDataFileVegeta(file, options)
+	if err != nil {
+		return err
+	}
+	return addParsedData(sink, parsed)
+}
+
+type artilleryParser struct{}
+
+func (artilleryParser) Name() string        { return "artillery" }
+func (artilleryParser) Description() string { return "Artillery JSON report" }
+
+func (artilleryParser) Detect(file string, head []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(head), []byte("{")) &&
+		(bytes.Contains(head, []byte(`"aggregate"`)) || bytes.Contains(head, []byte(`"intermediate"`)))
+}
+
+func (artilleryParser) Parse(file string, options ParseOptions, sink Sink) error {
+	if options.Samples {
+		return errSamplesNotSupported("artillery")
+	}
+
+	parsed, err := ParseDataFileArtillery(file)
+	if err != nil {
+		return err
+	}
+	return addParsedData(sink, parsed)
+}
+
+type wrkParser struct{}
+
+func (wrkParser) Name() string        { return "wrk" }
+func (wrkParser) Description() string { return "wrk or wrk2 output with --latency" }
+
+func (wrkParser) Detect(file string, head []byte) bool {
+	return wrkRunningPattern.MatchString(firstLine(head))
+}
+
+func (wrkParser) Parse(file string, options ParseOptions, sink Sink) error {
+	if options.Samples {
+		return errSamplesNotSupported("wrk")
+	}
+
+	parsed, err := ParseDataFileWrk(file, options)
+	if err != nil {
+		return err
+	}
+	return addParsedData(sink, parsed)
+}
+
+type heyParser struct{}
+
+func (heyParser) Name() string        { return "hey" }
+func (heyParser) Description() string { return "hey CSV output" }
+
+func (heyParser) Detect(file string, head []byte) bool {
+	return strings.HasPrefix(firstLine(head), "response-time,DNS+dialup")
+}
+
+func (heyParser) Parse(file string, options ParseOptions, sink Sink) error {
+	parsed, err := ParseDataFileHey(file, options)
+	if err != nil {
+		return err
+	}
+	return addParsedData(sink, parsed)
+}
+
+type abParser struct{}
+
+func (abParser) Name() string        { return "ab" }
+func (abParser) Description() string { return "ApacheBench gnuplot TSV" }
+
+func (abParser) Detect(file string, head []byte) bool {
+	return strings.HasPrefix(firstLine(head), "starttime\tseconds\tctime")
+}
+
+func (abParser) Parse(file string, options ParseOptions, sink Sink) error {
+	parsed, err := ParseDataFileAb(file, options)
+	if err != nil {
+		return err
+	}
+	return addParsedData(sink, parsed)
+}
+
+type siegeParser struct{}
+
+func (siegeParser) Name() string        { return "siege" }
+func (siegeParser) Description() string { return "siege log" }
+
+func (siegeParser) Detect(file string, head []byte) bool {
+	header := firstLine(head)
+	return strings.HasPrefix(header, "Date & Time") && strings.Contains(header, "Trans")
+}
+
+func (siegeParser) Parse(file string, options ParseOptions, sink Sink) error {
+	if options.Samples {
+		return errSamplesNotSupported("siege")
+	}
+
+	parsed, err := ParseDataFileSiege(file, options)
+	if err != nil {
+		return err
+	}
+	return addParsedData(sink, parsed)
+}
+
+type ghzParser struct{}
+
+func (ghzParser) Name() string        { return "ghz" }
+func (ghzParser) Description() string { return "ghz gRPC JSON report" }
+
+func (ghzParser) Detect(file string, head []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(head), []byte("{")) &&
+		(bytes.Contains(head, []byte(`"endReason"`)) || bytes.Contains(head, []byte(`"statusCodeDistribution"`)))
+}
+
+func (ghzParser) Parse(file string, options ParseOptions, sink Sink) error {
+	parsed, err := ParseDataFileGhz(file, options)
+	if err != nil {
+		return err
+	}
+	return addParsedData(sink, parsed)
+}
+
+type fortioParser struct{}
+
+func (fortioParser) Name() string        { return "fortio" }
+func (fortioParser) Description() string { return "Fortio JSON result" }
+
+func (fortioParser) Detect(file string, head []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(head), []byte("{")) &&
+		(bytes.Contains(head, []byte(`"RunType"`)) || bytes.Contains(head, []byte(`"DurationHistogram"`)))
+}
+
+func (fortioParser) Parse(file string, options ParseOptions, sink Sink) error {
+	if options.Samples {
+		return errSamplesNotSupported("fortio")
+	}
+
+	parsed, err := ParseDataFileFortio(file, options)
+	if err != nil {
+		return err
+	}
+	return addParsedData(sink, parsed)
+}
+
+type nbomberParser struct{}
+
+func (nbomberParser) Name() string        { return "nbomber" }
+func (nbomberParser) Description() string { return "NBomber CSV report or JSON node stats" }
+
+func (nbomberParser) Detect(file string, head []byte) bool {
+	return strings.HasPrefix(firstLine(head), "test_suite,test_name,scenario") ||
+		bytes.Contains(head, []byte(`"ScenarioStats"`))
+}
+
+func (nbomberParser) Parse(file string, options ParseOptions, sink Sink) error {
+	if options.Samples {
+		return errSamplesNotSupported("nbomber")
+	}
+
+	parsed, err := ParseDataFileNBomber(file, options)
+	if err != nil {
+		return err
+	}
+	return addParsedData(sink, parsed)
+}
+
+type tsungParser struct{}
+
+func (tsungParser) Name() string        { return "tsung" }
+func (tsungParser) Description() string { return "Tsung tsung.dump or tsung.log" }
+
+func (tsungParser) Detect(file string, head []byte) bool {
+	line := firstLine(head)
+	return strings.HasPrefix(line, "# stats: dump at") || strings.HasPrefix(line, "#date;pid;")
+}
+
+func (tsungParser) Parse(file string, options ParseOptions, sink Sink) error {
+	parsed, err := ParseDataFileTsung(file, options)
+	if err != nil {
+		return err
+	}
+	return addParsedData(sink, parsed)
+}
+
+func errSamplesNotSupported(format string) error {
+	return fmt.Errorf("format %s doesn't support publishing all samples", format)
+}
+
+func addRows(sink Sink, rows []UngroupedMetricDataPoint) {
+	for _, row := range rows {
+		sink.AddRow(row)
+	}
+}
+
+func addParsedData(sink Sink, parsed *ParsedData) error {
+	if len(parsed.Buckets) > 0 {
+		bucketSink, ok := sink.(BucketSink)
+		if !ok {
+			return errors.New("sink doesn't accept pre-aggregated buckets, implement parser.BucketSink")
+		}
+		for _, bucket := range parsed.Buckets {
+			bucketSink.AddBucket(bucket)
+		}
+	}
+
+	addRows(sink, parsed.Rows)
+	for _, sample := range parsed.Samples {
+		sink.AddSample(sample)
+	}
+	for _, point := range parsed.CustomMetrics {
+		sink.AddCustomMetric(point)
+	}
+	for _, event := range parsed.Errors {
+		sink.AddError(event)
+	}
+	if parsed.Metadata != nil {
+		sink.SetMetadata(parsed.Metadata)
+	}
+	if parsed.Summary != nil {
+		sink.SetSummary(parsed.Summary)
+	}
+	return nil
+}
+
+func firstLineBytes(head []byte) []byte {
+	if index := bytes.IndexByte(head, '\n'); index != -1 {
+		return head[:index]
+	}
+	return head
+}
+
+func firstLine(head []byte) string {
+	return strings.TrimPrefix(strings.TrimSpace(string(firstLineBytes(head))), "\ufeff")
+}
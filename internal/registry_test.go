@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type testParser struct{}
+
+func (testParser) Name() string        { return "test-format" }
+func (testParser) Description() string { return "rows used by tests" }
+
+func (testParser) Detect(file string, head []byte) bool {
+	return strings.HasPrefix(string(head), "test-format")
+}
+
+func (testParser) Parse(file string, options ParseOptions, sink Sink) error {
+	sink.AddRow(UngroupedMetricDataPoint{Label: "b", Requests: 1, TimeStamp: 2000})
+	sink.AddRow(UngroupedMetricDataPoint{Label: "a", Requests: 1, TimeStamp: 1000})
+	return nil
+}
+
+func init() {
+	RegisterParser(testParser{})
+}
+
+func TestDetectBuiltinFormats(t *testing.T) {
+	tests := []struct {
+		file     string
+		contents string
+		expected string
+	}{
+		{"results.jtl", "timeStamp,elapsed,label,responseCode\n", "jmeter"},
+		{"results.xml", "<?xml version=\"1.0\"?>\n<testResults version=\"1.2\">\n", "jmeter"},
+		{"results.json", "{\"type\":\"Metric\",\"data\":{\"name\":\"vus\"},\"metric\":\"vus\"}\n", "k6"},
+		{"results.csv", "metric_name,timestamp,metric_value\n", "k6"},
+		{"summary.json", "{\n  \"metrics\": {}\n}\n", "k6-summary"},
+		{"simulation.log", "RUN\tcheckout\tcheckout\t1647453612000\t \t3.7.6\n", "gatling"},
+		{"stats_history.csv", "Timestamp,User Count,Type,Name,Requests/s\n", "locust"},
+		{"results.json", "{\"attack\":\"\",\"seq\":0,\"code\":200,\"timestamp\":\"2022-03-16T18:00:12Z\",\"latency\":25000000,\"bytes_out\":0,\"bytes_in\":512}\n", "vegeta"},
+		{"results.csv", "1647453612123456789,200,25000000,0,512,,,,0,GET,https://example.com/,\n", "vegeta"},
+		{"report.json", "{\n  \"aggregate\": {\n    \"counters\": {}\n  }\n}\n", "artillery"},
+		{"wrk.txt", "Running 30s test @ http://127.0.0.1:8080/\n  2 threads and 100 connections\n", "wrk"},
+		{"hey.csv", "response-time,DNS+dialup,DNS,Request-write,Response-delay,Response-read,status-code,offset\n", "hey"},
+		{"ab.tsv", "starttime\tseconds\tctime\tdtime\tttime\twait\n", "ab"},
+		{"siege.log", "      Date & Time,  Trans,  Elap Time,  Data Trans,  Resp Time,  Trans Rate,  Throughput,  Concurrent,    OKAY,   Failed\n", "siege"},
+		{"ghz.json", "{\n  \"date\": \"2022-03-17T10:00:12Z\",\n  \"endReason\": \"normal\",\n  \"options\": {}\n}\n", "ghz"},
+		{"fortio.json", "{\n  \"RunType\": \"HTTP\",\n  \"Labels\": \"checkout\"\n}\n", "fortio"},
+		{"nbomber.csv", "test_suite,test_name,scenario,duration,step_name,request_count,ok,failed,rps,min,mean,max\n", "nbomber"},
+		{"nbomber.json", "{\"TestInfo\":{\"TestSuite\":\"nbomber\"},\"ScenarioStats\":[]}\n", "nbomber"},
+		{"tsung.dump", "#date;pid;id;http method;host;URL;HTTP status;size;duration;transaction;match;error;tag\n", "tsung"},
+		{"tsung.log", "# stats: dump at 1647511222\nstats: users 10 10\n", "tsung"},
+		{"other.log", "test-format\n", "test-format"},
+	}
+
+	dir := t.TempDir()
+	for _, test := range tests {
+		file := filepath.Join(dir, test.file)
+		if err := os.WriteFile(file, []byte(test.contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		parser, err := DetectParser(file)
+		if err != nil {
+			t.Error("Failed to detect format of ", test.file, ": ", err)
+			continue
+		}
+		if parser.Name() != test.expected {
+			t.Error("Detected ", parser.Name(), " for ", test.file, " expected: ", test.expected)
+		}
+	}
+}
+
+func TestParseDataFileRegisteredFormat(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "results.txt")
+	if err := os.WriteFile(file, []byte("test-format\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []string{"test-format", FormatAuto} {
+		parsed, err := ParseDataFile(file, format, ParseOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(parsed.Rows) != 2 || parsed.Rows[0].Label != "a" {
+			t.Error("Failed to read sorted rows with format ", format, ": ", parsed.Rows)
+		}
+	}
+
+	if _, err := ParseDataFile(file, "unknown", ParseOptions{}); err == nil {
+		t.Error("Failed to reject an unknown format")
+	}
+}
+
+func TestRegisterParserTwicePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Failed to panic when registering a format twice")
+		}
+	}()
+
+	RegisterParser(jmeterParser{})
+}
+
+// rowSink implements only Sink, like sinks written before BucketSink.
+type rowSink struct {
+	rows int
+}
+
+func (s *rowSink) AddRow(row UngroupedMetricDataPoint)     { s.rows++ }
+func (s *rowSink) AddSample(sample LingoSample)            {}
+func (s *rowSink) AddCustomMetric(point CustomMetricPoint) {}
+func (s *rowSink) AddError(event ErrorEvent)               {}
+func (s *rowSink) SetMetadata(metadata *RunMetadata)       {}
+func (s *rowSink) SetSummary(summary *SummaryData)         {}
+
+func TestParseWithoutBucketSink(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "stats_history.csv")
+	contents := strings.Join(sampleLocustHeaders, ",") + "\n" + strings.Join(sampleLocustRow, ",") + "\n"
+	if err := os.WriteFile(file, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sink := &rowSink{}
+	if err := (testParser{}).Parse(file, ParseOptions{}, sink); err != nil || sink.rows != 2 {
+		t.Error("Failed to parse rows without a bucket sink: ", err)
+	}
+
+	if err := (locustParser{}).Parse(file, ParseOptions{}, sink); err == nil {
+		t.Error("Failed to reject buckets without a bucket sink")
+	}
+
+	if err := (locustParser{}).Parse(file, ParseOptions{}, &ParsedData{}); err != nil {
+		t.Error("Failed to parse buckets into ParsedData: ", err)
+	}
+}
@@ -0,0 +1,240 @@
+package internal
+
+// Share of the peak load a bucket must reach to be part of the steady state.
+// Throughput is noisier than virtual users, so it gets a looser threshold.
+const (
+	steadyStateVirtualUserRatio = 0.9
+	steadyStateThroughputRatio  = 0.8
+	steadyStateSmoothingBuckets = 3
+	steadyStateMinBuckets       = 3
+)
+
+type SteadyStateWindow struct {
+	StartedAt uint64
+	StoppedAt uint64
+}
+
+// DetectSteadyState finds the window where load is flat, after ramp-up and
+// before ramp-down. It uses virtual users when the format provides them and
+// falls back to throughput otherwise.
+func DetectSteadyState(dataPoints []MetricDataPoint) (*SteadyStateWindow, bool) {
+	// the finest level gives the most precise window
+	var level TimeAggregationLevel
+	for _, dp := range dataPoints {
+		if dp.Label == "" && dp.Generator == "" && (level == Undefined || dp.TimeAggregationLevel.Duration() < level.Duration()) {
+			level = dp.TimeAggregationLevel
+		}
+	}
+
+	var series []MetricDataPoint
+	for _, dp := range dataPoints {
+		if dp.Label == "" && dp.Generator == "" && dp.TimeAggregationLevel == level {
+			series = append(series, dp)
+		}
+	}
+
+	if len(series) < steadyStateMinBuckets {
+		return nil, false
+	}
+
+	values := make([]float64, len(series))
+	for i, dp := range series {
+		values[i] = float64(dp.Requests)
+	}
+	// virtual user counts are already flat, only throughput needs smoothing
+	load := movingAverage(values, steadyStateSmoothingBuckets)
+	ratio := steadyStateThroughputRatio
+
+	for _, dp := range series {
+		if dp.VirtualUsers > 0 {
+			ratio = steadyStateVirtualUserRatio
+			for i, dp := range series {
+				load[i] = float64(dp.VirtualUsers)
+			}
+			break
+		}
+	}
+
+	var peak float64
+	for _, value := range load {
+		if value > peak {
+			peak = value
+		}
+	}
+
+	start, end := -1, -1
+	for i, value := range load {
+		if value >= peak*ratio {
+			if start == -1 {
+				start = i
+			}
+			end = i
+		}
+	}
+
+	if start == -1 || end-start+1 < steadyStateMinBuckets {
+		return nil, false
+	}
+
+	return &SteadyStateWindow{
+		StartedAt: series[start].TimeStamp,
+		StoppedAt: series[end].TimeStamp + level.Milliseconds(),
+	}, true
+}
+
+func movingAverage(values []float64, window int) []float64 {
+	averaged := make([]float64, len(values))
+	for i := range values {
+		from := i - window/2
+		to := i + window/2
+		if from < 0 {
+			from = 0
+		}
+		if to > len(values)-1 {
+			to = len(values) - 1
+		}
+
+		var sum float64
+		for _, value := range values[from : to+1] {
+			sum += value
+		}
+		averaged[i] = sum / float64(to-from+1)
+	}
+
+	return averaged
+}
+
+// CalculateMetricSummaryForWindow summarizes the rows that fall inside the
+// window, overall and by label.
+func CalculateMetricSummaryForWindow(rows []UngroupedMetricDataPoint, window *SteadyStateWindow) (MetricSummary, map[string]MetricSummary) {
+	overall := &GlobalDataCounter{}
+	byLabel := make(map[string]*GlobalDataCounter)
+	var windowRows []UngroupedMetricDataPoint
+	rowsByLabel := make(map[string][]UngroupedMetricDataPoint)
+
+	for _, row := range rows {
+		if row.TimeStamp < window.StartedAt || row.TimeStamp >= window.StoppedAt {
+			continue
+		}
+
+		var counters []*GlobalDataCounter
+		if row.Label != "" {
+			if byLabel[row.Label] == nil {
+				byLabel[row.Label] = &GlobalDataCounter{Transaction: row.Transaction}
+			}
+			// the label is a transaction only if all of its rows are
+			byLabel[row.Label].Transaction = byLabel[row.Label].Transaction && row.Transaction
+			counters = append(counters, byLabel[row.Label])
+			rowsByLabel[row.Label] = append(rowsByLabel[row.Label], row)
+		}
+		if !row.Transaction {
+			counters = append(counters, overall)
+			windowRows = append(windowRows, row)
+		}
+
+		for _, counter := range counters {
+			counter.TotalRequests += row.Requests
+			counter.TotalFailures += row.Failures
+			counter.TotalBytesReceived += row.BytesReceived
+			counter.TotalBytesSent += row.BytesSent
+			if row.Requests > 0 {
+				counter.RawLatencies = append(counter.RawLatencies, float64(row.Latency))
+				counter.RawTimings.add(row)
+				counter.Apdex.add(row)
+			}
+		}
+	}
+
+	overall.MaxVirtualUsers = peakVirtualUsers(windowRows)
+	applyWindowThroughput(overall, windowRows, window)
+	for label, counter := range byLabel {
+		counter.MaxVirtualUsers = peakVirtualUsers(rowsByLabel[label])
+		applyWindowThroughput(counter, rowsByLabel[label], window)
+	}
+
+	summary := calculateMetricSummary(overall, "")
+	summary.Scope = SteadyStateScope
+
+	summaryByLabel := make(map[string]MetricSummary)
+	for label, counter := range byLabel {
+		labelSummary := calculateMetricSummary(counter, label)
+		labelSummary.Scope = SteadyStateScope
+		summaryByLabel[label] = labelSummary
+	}
+
+	return summary, summaryByLabel
+}
+
+// applyWindowThroughput sets the time span and peak throughput of the window,
+// bucketed at the finest time aggregation level like the reducer.
+func applyWindowThroughput(counter *GlobalDataCounter, rows []UngroupedMetricDataPoint, window *SteadyStateWindow) {
+	level := allTimeAggregationLevels[0]
+	interval := level.Milliseconds()
+	counter.FirstTimeStamp = window.StartedAt
+	counter.LastTimeStamp = window.StartedAt
+	if window.StoppedAt >= window.StartedAt+interval {
+		counter.LastTimeStamp = window.StoppedAt - interval
+	}
+
+	type bucket struct {
+		requests      uint64
+		bytesReceived uint64
+		bytesSent     uint64
+	}
+
+	buckets := make(map[uint64]*bucket)
+	for _, row := range rows {
+		timeStamp := calculateIntervalFloor(row.TimeStamp, interval)
+		if buckets[timeStamp] == nil {
+			buckets[timeStamp] = &bucket{}
+		}
+		buckets[timeStamp].requests += row.Requests
+		buckets[timeStamp].bytesReceived += row.BytesReceived
+		buckets[timeStamp].bytesSent += row.BytesSent
+	}
+
+	for _, b := range buckets {
+		throughput := calculateThroughput(b.requests, b.bytesReceived, b.bytesSent, level.Duration())
+		if throughput.RequestsPerSecond > counter.PeakRequestsPerSecond {
+			counter.PeakRequestsPerSecond = throughput.RequestsPerSecond
+		}
+		if throughput.BytesReceivedPerSecond > counter.PeakBytesReceivedPerSecond {
+			counter.PeakBytesReceivedPerSecond = throughput.BytesReceivedPerSecond
+		}
+		if throughput.BytesSentPerSecond > counter.PeakBytesSentPerSecond {
+			counter.PeakBytesSentPerSecond = throughput.BytesSentPerSecond
+		}
+	}
+}
+
+// peakVirtualUsers sums the virtual users of each generator per bucket at the
+// finest time aggregation level, matching how the reducer counts virtual users.
+// Generators rarely write rows at the same time stamp, so summing per time
+// stamp would only count one of them.
+func peakVirtualUsers(rows []UngroupedMetricDataPoint) uint64 {
+	interval := allTimeAggregationLevels[0].Milliseconds()
+	perBucket := make(map[uint64]map[string]uint64)
+
+	for _, row := range rows {
+		bucket := calculateIntervalFloor(row.TimeStamp, interval)
+		if perBucket[bucket] == nil {
+			perBucket[bucket] = make(map[string]uint64)
+		}
+		if row.VirtualUsers > perBucket[bucket][row.Generator] {
+			perBucket[bucket][row.Generator] = row.VirtualUsers
+		}
+	}
+
+	var peak uint64
+	for _, perGenerator := range perBucket {
+		var total uint64
+		for _, virtualUsers := range perGenerator {
+			total += virtualUsers
+		}
+		if total > peak {
+			peak = total
+		}
+	}
+
+	return peak
+}
@@ -0,0 +1,48 @@
+package internal
+
+import "testing"
+
+func TestDetectSteadyState(t *testing.T) {
+	virtualUsers := []uint64{1, 5, 10, 20, 20, 20, 20, 20, 10, 2}
+	var dataPoints []MetricDataPoint
+	for i, vus := range virtualUsers {
+		dataPoints = append(dataPoints, MetricDataPoint{
+			TimeStamp:            uint64(1000000 + i*5000),
+			TimeAggregationLevel: FiveSeconds,
+			Requests:             vus * 10,
+			VirtualUsers:         vus,
+		})
+	}
+
+	window, ok := DetectSteadyState(dataPoints)
+	if !ok {
+		t.Fatal("Failed to detect steady state")
+	}
+
+	if window.StartedAt != 1015000 {
+		t.Error("Failed to detect steady state start: ", window.StartedAt, " expected: ", 1015000)
+	}
+
+	if window.StoppedAt != 1040000 {
+		t.Error("Failed to detect steady state stop: ", window.StoppedAt, " expected: ", 1040000)
+	}
+}
+
+func TestCalculateMetricSummaryForWindowSumsGenerators(t *testing.T) {
+	// generators write rows at different time stamps within the same bucket
+	rows := []UngroupedMetricDataPoint{
+		{TimeStamp: 1000000, Requests: 1, VirtualUsers: 10, Label: "a", Generator: "a.jtl"},
+		{TimeStamp: 1000400, Requests: 1, VirtualUsers: 8, Label: "a", Generator: "b.jtl"},
+		{TimeStamp: 1001000, Requests: 1, VirtualUsers: 10, Label: "a", Generator: "a.jtl"},
+		{TimeStamp: 1001700, Requests: 1, VirtualUsers: 8, Label: "a", Generator: "b.jtl"},
+	}
+
+	summary, summaryByLabel := CalculateMetricSummaryForWindow(rows, &SteadyStateWindow{StartedAt: 1000000, StoppedAt: 1002000})
+	if summary.MaxVirtualUsers != 18 {
+		t.Error("Failed to sum virtual users across generators: ", summary.MaxVirtualUsers, " expected: ", 18)
+	}
+
+	if summaryByLabel["a"].MaxVirtualUsers != 18 {
+		t.Error("Failed to sum labeled virtual users across generators: ", summaryByLabel["a"].MaxVirtualUsers, " expected: ", 18)
+	}
+}
@@ -0,0 +1,40 @@
+// Package parser lets Go programs that embed the CLI add results formats.
+//
+// A format registered from an init function is available to --format and to
+// --format auto, like the built-in formats:
+//
+//	func init() {
+//		parser.Register(myFormat{})
+//	}
+package parser
+
+import "github.com/latency-lingo/cli/internal"
+
+type (
+	Parser = internal.Parser
+	Sink   = internal.Sink
+	// BucketSink is optional, sinks without it can't read pre-aggregated
+	// formats such as Locust or Artillery.
+	BucketSink = internal.BucketSink
+	Options    = internal.ParseOptions
+
+	// Row is a single request, with its time stamp in milliseconds.
+	Row               = internal.UngroupedMetricDataPoint
+	Sample            = internal.LingoSample
+	Bucket            = internal.AggregatedBucket
+	HistogramBin      = internal.HistogramBin
+	CustomMetricPoint = internal.CustomMetricPoint
+	ErrorEvent        = internal.ErrorEvent
+	RunMetadata       = internal.RunMetadata
+	SummaryData       = internal.SummaryData
+)
+
+// Register adds a format. It panics if a format with the same name exists.
+func Register(p Parser) {
+	internal.RegisterParser(p)
+}
+
+// Names lists the registered formats sorted by name.
+func Names() []string {
+	return internal.ParserNames()
+}
@@ -0,0 +1,276 @@
+// Package publish publishes results straight from Go load tests, without
+// writing a results file first.
+//
+//	run, err := publish.NewRun(publish.Options{APIKey: apiKey, Label: "checkout flow"})
+//	if err != nil {
+//		return err
+//	}
+//	// from any number of goroutines
+//	run.Record(publish.Sample{TimeStamp: start, Label: "GET /cart", Latency: elapsed})
+//	result, err := run.Finish()
+package publish
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/latency-lingo/cli/internal"
+)
+
+// Options configure a run. APIKey and Label are required.
+type Options struct {
+	APIKey string
+	// Label is the test scenario name, like --label.
+	Label string
+	// RunName is shown instead of the generated run name when set.
+	RunName string
+	// Environment is production or development. Defaults to production.
+	Environment string
+	// ConfigFile holds label rules, filters and Apdex thresholds, like --config.
+	ConfigFile string
+	// Host overrides the API host of the environment, eg. for a proxy.
+	Host string
+}
+
+// Sample is a single request.
+type Sample struct {
+	TimeStamp      time.Time
+	Label          string
+	Latency        time.Duration
+	Failed         bool
+	ResponseCode   string
+	FailureMessage string
+	BytesReceived  uint64
+	BytesSent      uint64
+	VirtualUsers   uint64
+	// Generator identifies the load generator when several publish to the
+	// same run.
+	Generator string
+}
+
+// Summary holds the totals and latency percentiles of the run.
+type Summary = internal.MetricSummary
+
+type Result struct {
+	RunID     string
+	ReportURL string
+	Summary   Summary
+}
+
+// Run is a test run being recorded. Its methods are safe to call from
+// several goroutines.
+type Run struct {
+	options  Options
+	host     string
+	config   *internal.Config
+	testRun  *internal.TestRun
+	mu       sync.Mutex
+	rows     []internal.UngroupedMetricDataPoint
+	finished bool
+	// finishing is set while Finish publishes, samples recorded meanwhile are
+	// dropped
+	finishing bool
+	// published holds the steps of a failed Finish that went through, a
+	// retry skips them so metrics aren't sent twice
+	published publishSteps
+}
+
+// publishSteps are the requests Finish makes, in order.
+type publishSteps uint8
+
+const (
+	publishedChartMetrics publishSteps = 1 << iota
+	publishedErrorMetrics
+	publishedSummaryMetrics
+)
+
+// reducerMu serializes Finish, the reducer keeps run totals in package state.
+var reducerMu sync.Mutex
+
+// NewRun creates a run starting now.
+func NewRun(options Options) (*Run, error) {
+	if options.APIKey == "" || options.Label == "" {
+		return nil, errors.New("publish: APIKey and Label are required")
+	}
+
+	if options.Environment == "" {
+		options.Environment = internal.EnvironmentProduction
+	}
+
+	host := options.Host
+	if host == "" {
+		environmentHost, err := internal.APIHost(options.Environment)
+		if err != nil {
+			return nil, err
+		}
+		host = environmentHost
+	}
+
+	config, err := internal.LoadConfig(options.ConfigFile)
+	if err != nil {
+		return nil, err
+	}
+
+	testRun, err := internal.CreateTestRun(host, internal.CreateTestRunRequestData{
+		ApiKey:          options.APIKey,
+		ScenarioName:    options.Label,
+		RunName:         options.RunName,
+		StartedAt:       uint64(time.Now().Unix()),
+		PublishStrategy: "sdk",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Run{options: options, host: host, config: config, testRun: testRun}, nil
+}
+
+func (r *Run) ID() string {
+	return r.testRun.ID
+}
+
+// Record adds a sample. Samples recorded after Finish are dropped, as are
+// samples recorded after a failed Finish published part of the run.
+func (r *Run) Record(sample Sample) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.finished && !r.finishing && r.published == 0 {
+		r.rows = append(r.rows, toRow(sample))
+	}
+}
+
+// RecordBatch adds samples under a single lock, for hot loops.
+func (r *Run) RecordBatch(samples []Sample) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.finished || r.finishing || r.published != 0 {
+		return
+	}
+	for _, sample := range samples {
+		r.rows = append(r.rows, toRow(sample))
+	}
+}
+
+// Finish reduces the recorded samples the same way as the publish command,
+// publishes them and marks the run as stopped. When it fails the samples are
+// kept and the run stays open, so Finish can be retried. A retry only sends
+// what the failed attempt didn't.
+func (r *Run) Finish() (*Result, error) {
+	r.mu.Lock()
+	if r.finished || r.finishing {
+		r.mu.Unlock()
+		return nil, errors.New("publish: run already finished")
+	}
+	r.finishing = true
+	rows := r.rows
+	published := r.published
+	r.mu.Unlock()
+
+	result, err := r.publish(rows, &published)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.finishing = false
+	r.published = published
+	if err != nil {
+		return nil, err
+	}
+	r.finished = true
+	r.rows = nil
+	return result, nil
+}
+
+func (r *Run) publish(rows []internal.UngroupedMetricDataPoint, published *publishSteps) (*Result, error) {
+	// sort and filter a copy, the recorded rows are kept for retries
+	rows = append([]internal.UngroupedMetricDataPoint(nil), rows...)
+
+	// goroutines record out of order
+	sort.SliceStable(rows, func(i int, j int) bool {
+		return rows[i].TimeStamp < rows[j].TimeStamp
+	})
+
+	filter, err := internal.NewFilter(r.config.Filters)
+	if err != nil {
+		return nil, err
+	}
+	rows = internal.FilterDataPoints(rows, filter)
+	if len(rows) == 0 {
+		return nil, errors.New("publish: no samples recorded")
+	}
+
+	normalizer, err := internal.NewLabelNormalizer(r.config.Labels)
+	if err != nil {
+		return nil, err
+	}
+	internal.NormalizeDataPointLabels(rows, normalizer)
+	internal.ApplyApdexThresholds(rows, r.config.Apdex)
+
+	reducerMu.Lock()
+	defer reducerMu.Unlock()
+
+	token := r.testRun.WriteToken
+	groupedResult := internal.GroupAllDataPoints(rows)
+	dataPoints := groupedResult.DataPoints
+	for _, generatorDataPoints := range groupedResult.DataPointsByGenerator {
+		dataPoints = append(dataPoints, generatorDataPoints...)
+	}
+
+	if *published&publishedChartMetrics == 0 {
+		if _, err := internal.CreateTestChartMetrics(r.host, token, dataPoints, groupedResult.DataPointsByLabel); err != nil {
+			return nil, err
+		}
+		*published |= publishedChartMetrics
+	}
+
+	if errorEvents := internal.ErrorEventsFromRows(rows); len(errorEvents) > 0 && *published&publishedErrorMetrics == 0 {
+		errorBreakdown := internal.CalculateErrorBreakdown(rows, errorEvents)
+		if _, err := internal.CreateTestErrorMetrics(r.host, token, errorBreakdown); err != nil {
+			return nil, err
+		}
+		*published |= publishedErrorMetrics
+	}
+
+	// the summary is recalculated on retries, the recorded rows don't change
+	// once part of the run is published
+	summary := internal.CalculateMetricSummaryOverall()
+	if *published&publishedSummaryMetrics == 0 {
+		if _, err := internal.CreateTestSummaryMetrics(r.host, token, summary, internal.CalculateMetricSummaryByLabel()); err != nil {
+			return nil, err
+		}
+		*published |= publishedSummaryMetrics
+	}
+
+	// rows are stamped in milliseconds, runs in seconds
+	if _, err := internal.UpdateTestRun(r.host, token, rows[len(rows)-1].TimeStamp/1000); err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		RunID:     r.testRun.ID,
+		ReportURL: internal.ReportURL(r.options.Environment, r.testRun.ID),
+		Summary:   summary,
+	}, nil
+}
+
+func toRow(sample Sample) internal.UngroupedMetricDataPoint {
+	row := internal.UngroupedMetricDataPoint{
+		Requests:       1,
+		VirtualUsers:   sample.VirtualUsers,
+		TimeStamp:      uint64(sample.TimeStamp.UnixMilli()),
+		Latency:        uint64(sample.Latency.Milliseconds()),
+		Label:          sample.Label,
+		ResponseCode:   sample.ResponseCode,
+		Generator:      sample.Generator,
+		FailureMessage: sample.FailureMessage,
+		BytesReceived:  sample.BytesReceived,
+		BytesSent:      sample.BytesSent,
+	}
+	if sample.Failed {
+		row.Failures = 1
+	}
+	return row
+}
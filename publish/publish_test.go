@@ -0,0 +1,150 @@
+package publish
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunRecordsConcurrently(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		paths []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+
+		if r.URL.Path == "/v2/test.createRun" {
+			w.Write([]byte(`{"result":{"success":true,"data":{"id":"run-1","writeToken":"token"}}}`))
+		}
+	}))
+	defer server.Close()
+
+	run, err := NewRun(Options{APIKey: "key", Label: "checkout", Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.UnixMilli(1647453612000)
+	var wg sync.WaitGroup
+	for worker := 0; worker < 4; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; i < 25; i++ {
+				run.Record(Sample{
+					TimeStamp: start.Add(time.Duration(i) * time.Second),
+					Label:     "GET /cart",
+					Latency:   time.Duration(100+worker) * time.Millisecond,
+					Failed:    worker == 0,
+				})
+			}
+		}(worker)
+	}
+	wg.Wait()
+
+	result, err := run.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.RunID != "run-1" || result.Summary.TotalRequests != 100 || result.Summary.TotalFailures != 25 {
+		t.Error("Failed to publish totals: ", result.RunID, result.Summary.TotalRequests, result.Summary.TotalFailures, " expected: ", "run-1", 100, 25)
+	}
+
+	if paths[len(paths)-1] != "/v2/test.updateRun" {
+		t.Error("Failed to stop the run, last request: ", paths[len(paths)-1])
+	}
+
+	if _, err := run.Finish(); err == nil {
+		t.Error("Failed to reject finishing a run twice")
+	}
+}
+
+func TestRunFinishCanBeRetried(t *testing.T) {
+	failSummary := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/test.createRun":
+			w.Write([]byte(`{"result":{"success":true,"data":{"id":"run-1","writeToken":"token"}}}`))
+		case "/v2/test.createSummaryMetrics":
+			if failSummary {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}
+	}))
+	defer server.Close()
+
+	run, err := NewRun(Options{APIKey: "key", Label: "checkout", Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run.Record(Sample{TimeStamp: time.UnixMilli(1647453612000), Label: "GET /cart", Latency: 100 * time.Millisecond})
+
+	if _, err := run.Finish(); err == nil {
+		t.Fatal("Failed to return the publish error")
+	}
+
+	failSummary = false
+	result, err := run.Finish()
+	if err != nil {
+		t.Fatal("Failed to retry Finish: ", err)
+	}
+
+	if result.Summary.TotalRequests != 1 {
+		t.Error("Failed to keep samples for the retry: ", result.Summary.TotalRequests, " expected: ", 1)
+	}
+}
+
+func TestRunFinishRetrySkipsPublishedMetrics(t *testing.T) {
+	var (
+		failErrors = true
+		requests   = make(map[string]int)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		switch r.URL.Path {
+		case "/v2/test.createRun":
+			w.Write([]byte(`{"result":{"success":true,"data":{"id":"run-1","writeToken":"token"}}}`))
+		case "/v2/test.createErrorMetrics":
+			if failErrors {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}
+	}))
+	defer server.Close()
+
+	run, err := NewRun(Options{APIKey: "key", Label: "checkout", Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.UnixMilli(1647453612000)
+	run.Record(Sample{TimeStamp: start, Label: "GET /cart", Latency: 100 * time.Millisecond})
+	run.Record(Sample{TimeStamp: start, Label: "GET /cart", Latency: 300 * time.Millisecond, Failed: true, ResponseCode: "500"})
+
+	if _, err := run.Finish(); err == nil {
+		t.Fatal("Failed to return the publish error")
+	}
+
+	// dropped, the chart metrics of the run are already published
+	run.Record(Sample{TimeStamp: start.Add(time.Second), Label: "GET /cart", Latency: 100 * time.Millisecond})
+
+	failErrors = false
+	result, err := run.Finish()
+	if err != nil {
+		t.Fatal("Failed to retry Finish: ", err)
+	}
+
+	if requests["/v2/test.createChartMetrics"] != 1 || requests["/v2/test.createErrorMetrics"] != 2 || requests["/v2/test.createSummaryMetrics"] != 1 || requests["/v2/test.updateRun"] != 1 {
+		t.Error("Failed to skip published metrics on retry: ", requests)
+	}
+	if result.Summary.TotalRequests != 2 || result.Summary.TotalFailures != 1 {
+		t.Error("Failed to keep samples for the retry: ", result.Summary.TotalRequests, result.Summary.TotalFailures, " expected: ", 2, 1)
+	}
+}